@@ -0,0 +1,292 @@
+package sqluct
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errInvalidArray = errors.New("invalid array literal")
+
+// scanArray decodes array literal into a slice pointer.
+func scanArray(dst reflect.Value, src interface{}) error {
+	var lit string
+
+	switch v := src.(type) {
+	case nil:
+		dst.Set(reflect.Zero(dst.Type()))
+
+		return nil
+	case []byte:
+		lit = string(v)
+	case string:
+		lit = v
+	default:
+		return fmt.Errorf("%w: unexpected type %T", errInvalidArray, src)
+	}
+
+	elems, err := parseArray(lit)
+	if err != nil {
+		return err
+	}
+
+	res := reflect.MakeSlice(dst.Type(), len(elems), len(elems))
+
+	for i, e := range elems {
+		if e == nil {
+			continue
+		}
+
+		if err := scanArrayElem(res.Index(i), *e); err != nil {
+			return fmt.Errorf("array element %d: %w", i, err)
+		}
+	}
+
+	dst.Set(res)
+
+	return nil
+}
+
+// parseArray splits one-dimensional array literal into elements, nil elements are NULL.
+func parseArray(lit string) ([]*string, error) {
+	if len(lit) < 2 || lit[0] != '{' || lit[len(lit)-1] != '}' {
+		return nil, fmt.Errorf("%w: %q", errInvalidArray, lit)
+	}
+
+	lit = lit[1 : len(lit)-1]
+	if lit == "" {
+		return []*string{}, nil
+	}
+
+	var res []*string
+
+	for pos := 0; pos <= len(lit); pos++ {
+		var (
+			e      strings.Builder
+			quoted = pos < len(lit) && lit[pos] == '"'
+		)
+
+		if quoted {
+			for pos++; pos < len(lit) && lit[pos] != '"'; pos++ {
+				if lit[pos] == '\\' {
+					pos++
+				}
+
+				if pos < len(lit) {
+					e.WriteByte(lit[pos])
+				}
+			}
+
+			if pos >= len(lit) {
+				return nil, fmt.Errorf("%w: unterminated quoted element", errInvalidArray)
+			}
+
+			pos++
+		} else {
+			for ; pos < len(lit) && lit[pos] != ','; pos++ {
+				if lit[pos] == '{' || lit[pos] == '"' {
+					return nil, fmt.Errorf("%w: unexpected %q at %d", errInvalidArray, lit[pos], pos+1)
+				}
+
+				e.WriteByte(lit[pos])
+			}
+		}
+
+		if pos < len(lit) && lit[pos] != ',' {
+			return nil, fmt.Errorf("%w: unexpected %q at %d", errInvalidArray, lit[pos], pos+1)
+		}
+
+		s := e.String()
+
+		if !quoted && strings.EqualFold(s, "NULL") {
+			res = append(res, nil)
+		} else {
+			res = append(res, &s)
+		}
+	}
+
+	return res, nil
+}
+
+// scanArrayElem decodes text representation of array element into value.
+func scanArrayElem(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+
+	if v.Addr().Type().Implements(scannerType) {
+		return v.Addr().Interface().(sql.Scanner).Scan(s) //nolint:errcheck // Checked with Implements.
+	}
+
+	if t, ok := v.Addr().Interface().(*time.Time); ok {
+		return scanArrayTime(t, s)
+	}
+
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+		b, err := hex.DecodeString(strings.TrimPrefix(s, `\x`))
+		if err != nil {
+			return err
+		}
+
+		v.SetBytes(b)
+
+		return nil
+	}
+
+	switch v.Kind() { //nolint:exhaustive // Unsupported kinds are handled in default.
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		v.SetBool(s == "t" || s == "true")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type()) //nolint:goerr113
+	}
+
+	return nil
+}
+
+// arrayTimeLayouts are formats of time elements, RFC3339 is written by Value and others are used by Postgres.
+var arrayTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00:00",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// scanArrayTime decodes time element of array.
+func scanArrayTime(t *time.Time, s string) error {
+	var err error
+
+	for _, layout := range arrayTimeLayouts {
+		var v time.Time
+
+		if v, err = time.Parse(layout, s); err == nil {
+			*t = v
+
+			return nil
+		}
+	}
+
+	return err
+}
+
+// pgArray is a slice or array argument encoded as Postgres array literal.
+type pgArray struct {
+	v reflect.Value
+}
+
+// Value encodes array literal.
+func (a pgArray) Value() (driver.Value, error) {
+	res := strings.Builder{}
+
+	res.WriteString("{")
+
+	for i := 0; i < a.v.Len(); i++ {
+		if i != 0 {
+			res.WriteString(",")
+		}
+
+		if err := writeArrayElem(&res, a.v.Index(i).Interface(), "NULL"); err != nil {
+			return nil, fmt.Errorf("array element %d: %w", i, err)
+		}
+	}
+
+	res.WriteString("}")
+
+	return res.String(), nil
+}
+
+// writeArrayElem writes element of array or composite literal, null is a representation of NULL.
+func writeArrayElem(res *strings.Builder, e interface{}, null string) error {
+	if dv, ok := e.(driver.Valuer); ok {
+		v, err := dv.Value()
+		if err != nil {
+			return err
+		}
+
+		e = v
+	}
+
+	v := reflect.ValueOf(e)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if !v.IsValid() || v.Kind() == reflect.Ptr {
+		res.WriteString(null)
+
+		return nil
+	}
+
+	switch val := v.Interface().(type) {
+	case []byte:
+		writeArrayString(res, `\x`+hex.EncodeToString(val))
+
+		return nil
+	case time.Time:
+		writeArrayString(res, val.Format(time.RFC3339Nano))
+
+		return nil
+	}
+
+	switch v.Kind() { //nolint:exhaustive // Unsupported kinds are handled in default.
+	case reflect.String:
+		writeArrayString(res, v.String())
+	case reflect.Bool:
+		res.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		res.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		res.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		res.WriteString(strconv.FormatFloat(v.Float(), 'g', -1, 64))
+	default:
+		return fmt.Errorf("unsupported type %T", e) //nolint:goerr113
+	}
+
+	return nil
+}
+
+func writeArrayString(res *strings.Builder, s string) {
+	res.WriteString(`"`)
+
+	for _, r := range s {
+		if r == '"' || r == '\\' {
+			res.WriteRune('\\')
+		}
+
+		res.WriteRune(r)
+	}
+
+	res.WriteString(`"`)
+}
@@ -0,0 +1,32 @@
+//go:build go1.18
+// +build go1.18
+
+package sqluct
+
+import (
+	"database/sql/driver"
+	"reflect"
+)
+
+// Array is a one-dimensional Postgres array column, e.g. `text[]` or `int[]`.
+//
+// Unlike plain slices, which are expanded to `IN (?,?,...)` lists in conditions, Array is always bound as a single
+// array parameter: in Insert and Update, and in WhereEq or Where as `col = ?` or with array operators
+// defined in field tag (see Mapper.Where).
+//
+//	Tags sqluct.Array[string] `db:"tags"`
+type Array[T any] []T
+
+// Value encodes array literal.
+func (a Array[T]) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+
+	return pgArray{v: reflect.ValueOf([]T(a))}.Value()
+}
+
+// Scan decodes array literal.
+func (a *Array[T]) Scan(src interface{}) error {
+	return scanArray(reflect.ValueOf(a).Elem(), src)
+}
@@ -0,0 +1,139 @@
+//go:build go1.18
+// +build go1.18
+
+package sqluct_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/bool64/sqluct"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArray_Value(t *testing.T) {
+	v, err := sqluct.Array[string]{"a", `b"\`, "c d"}.Value()
+	require.NoError(t, err)
+	assert.Equal(t, `{"a","b\"\\","c d"}`, v)
+
+	v, err = sqluct.Array[*int]{nil}.Value()
+	require.NoError(t, err)
+	assert.Equal(t, `{NULL}`, v)
+
+	v, err = sqluct.Array[int](nil).Value()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+}
+
+func TestArray_Scan(t *testing.T) {
+	var s sqluct.Array[string]
+
+	require.NoError(t, s.Scan([]byte(`{a,"b\"\\","c d",NULL,"NULL",""}`)))
+	assert.Equal(t, sqluct.Array[string]{"a", `b"\`, "c d", "", "NULL", ""}, s)
+
+	require.NoError(t, s.Scan("{}"))
+	assert.Equal(t, sqluct.Array[string]{}, s)
+
+	require.NoError(t, s.Scan(nil))
+	assert.Nil(t, s)
+
+	var p sqluct.Array[*int64]
+
+	require.NoError(t, p.Scan("{1,NULL,-3}"))
+	require.Len(t, p, 3)
+	assert.Equal(t, int64(1), *p[0])
+	assert.Nil(t, p[1])
+	assert.Equal(t, int64(-3), *p[2])
+
+	var b sqluct.Array[bool]
+
+	require.NoError(t, b.Scan("{t,f}"))
+	assert.Equal(t, sqluct.Array[bool]{true, false}, b)
+
+	var f sqluct.Array[float64]
+
+	require.NoError(t, f.Scan("{1.5,2}"))
+	assert.Equal(t, sqluct.Array[float64]{1.5, 2}, f)
+
+	var i sqluct.Array[int]
+
+	assert.EqualError(t, i.Scan("{1,a}"), `array element 1: strconv.ParseInt: parsing "a": invalid syntax`)
+	assert.EqualError(t, i.Scan("1,2"), `invalid array literal: "1,2"`)
+	assert.EqualError(t, i.Scan(`{"1}`), `invalid array literal: unterminated quoted element`)
+	assert.EqualError(t, i.Scan(`{{1},{2}}`), `invalid array literal: unexpected '{' at 1`)
+	assert.EqualError(t, i.Scan(1), `invalid array literal: unexpected type int`)
+}
+
+func TestArray_time(t *testing.T) {
+	ts := sqluct.Array[time.Time]{
+		time.Date(2020, 1, 2, 3, 4, 5, 123456789, time.UTC),
+		time.Date(2021, 6, 7, 8, 9, 10, 0, time.FixedZone("", 3*3600+1800)),
+	}
+
+	v, err := ts.Value()
+	require.NoError(t, err)
+	assert.Equal(t, `{"2020-01-02T03:04:05.123456789Z","2021-06-07T08:09:10+03:30"}`, v)
+
+	var res sqluct.Array[time.Time]
+
+	require.NoError(t, res.Scan(v))
+	require.Len(t, res, 2)
+	assert.True(t, ts[0].Equal(res[0]))
+	assert.True(t, ts[1].Equal(res[1]))
+
+	// Postgres text output of timestamptz[], timestamp[] and date[].
+	require.NoError(t, res.Scan(`{"2020-01-02 03:04:05.123+00","2021-06-07 08:09:10+03:30",NULL}`))
+	require.Len(t, res, 3)
+	assert.True(t, time.Date(2020, 1, 2, 3, 4, 5, 123000000, time.UTC).Equal(res[0]))
+	assert.True(t, ts[1].Equal(res[1]))
+	assert.True(t, res[2].IsZero())
+
+	require.NoError(t, res.Scan(`{"2020-01-02 03:04:05",2020-01-02}`))
+	assert.Equal(t, sqluct.Array[time.Time]{
+		time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+	}, res)
+
+	var p sqluct.Array[*time.Time]
+
+	require.NoError(t, p.Scan(`{NULL,"2020-01-02T03:04:05Z"}`))
+	require.Len(t, p, 2)
+	assert.Nil(t, p[0])
+	assert.True(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC).Equal(*p[1]))
+
+	assert.EqualError(t, res.Scan(`{foo}`), `array element 0: parsing time "foo" as "2006-01-02": cannot parse "foo" as "2006"`)
+}
+
+func TestMapper_array(t *testing.T) {
+	type Post struct {
+		ID   int                  `db:"id"`
+		Tags sqluct.Array[string] `db:"tags"`
+	}
+
+	type Filter struct {
+		Tag     string               `db:"tag,any=tags,omitempty"`
+		AnyTags sqluct.Array[string] `db:"tags,overlap,omitempty"`
+		IDs     []int                `db:"id,omitempty"`
+	}
+
+	sm := sqluct.Mapper{Dialect: sqluct.DialectPostgres}
+	tags := sqluct.Array[string]{"a", "b"}
+
+	assertStatementArgs(t, "INSERT INTO posts (id,tags) VALUES (?,?)", []interface{}{1, tags},
+		sm.Insert(squirrel.Insert("posts"), Post{ID: 1, Tags: tags}))
+
+	assertStatementArgs(t, "UPDATE posts SET id = ?, tags = ?", []interface{}{1, tags},
+		sm.Update(squirrel.Update("posts"), Post{ID: 1, Tags: tags}))
+
+	assertStatementArgs(t, "SELECT id FROM posts WHERE tags = ?", []interface{}{`{"a","b"}`},
+		squirrel.Select("id").From("posts").Where(sm.WhereEq(Post{Tags: tags}, sqluct.Columns("tags"))))
+
+	assertStatementArgs(t, "SELECT id FROM posts WHERE (tags = ?)", []interface{}{`{"a","b"}`},
+		squirrel.Select("id").From("posts").Where(sm.Where(Post{Tags: tags}, sqluct.Columns("tags"), sqluct.UseAnyArray)))
+
+	assertStatementArgs(t, "SELECT id FROM posts WHERE (? = ANY(tags) AND tags && ? AND id IN (?,?))",
+		[]interface{}{"a", tags, 1, 2},
+		squirrel.Select("id").From("posts").Where(sm.Where(Filter{Tag: "a", AnyTags: tags, IDs: []int{1, 2}})))
+}
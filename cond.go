@@ -0,0 +1,136 @@
+package sqluct
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// Conditions accumulates optional conditions, it is immutable, every call returns a new instance.
+//
+//	q = q.Where(sqluct.Cond().
+//		When(req.Name != "", squirrel.Like{rf.Ref(&row.Name): req.Name + "%"}).
+//		When(req.Role != nil, rf.Eq(&row.Role, req.Role)).
+//		And())
+type Conditions struct {
+	and squirrel.And
+}
+
+// Cond creates empty Conditions.
+func Cond() Conditions {
+	return Conditions{}
+}
+
+// When adds condition if pred is true, nil condition is ignored.
+func (c Conditions) When(pred bool, cond squirrel.Sqlizer) Conditions {
+	if !pred || cond == nil {
+		return c
+	}
+
+	c.and = append(c.and[:len(c.and):len(c.and)], cond)
+
+	return c
+}
+
+// And returns accumulated conditions combined with AND.
+//
+// Empty conditions are rendered as `(1=1)`.
+func (c Conditions) And() squirrel.And {
+	return c.and
+}
+
+// ToSql implements squirrel.Sqlizer.
+func (c Conditions) ToSql() (string, []interface{}, error) { //nolint // Method name matches ext. implementation.
+	return c.And().ToSql()
+}
+
+var errUnknownParameter = errors.New("unknown parameter")
+
+// WhereFromValues maps URL query parameters to conditions on whitelisted columns.
+//
+// Field map defines parameter names and respective column references, e.g.
+// map[string]string{"name": rf.Ref(&row.Name), "age": rf.Ref(&row.Age)}.
+// Parameter name can have operator suffix: `_eq`, `_ne`, `_gt`, `_ge`, `_lt`, `_le`, `_like`, `_ilike` or `_in`.
+// Values are bind arguments, multiple values of a parameter without suffix (or with `_eq`, `_ne`) make IN (NOT IN)
+// condition, `_in` values are also split by comma, other operators make a condition for every value.
+//
+//	// ?name_like=J%&age_gt=18&role_in=admin,owner
+//	cond, err := sqluct.WhereFromValues(r.URL.Query(), fields, true)
+//
+// Unknown parameters fail with error in strict mode and are ignored otherwise.
+func WhereFromValues(values url.Values, fieldMap map[string]string, strict bool) (squirrel.And, error) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	res := make(squirrel.And, 0, len(keys))
+
+	for _, k := range keys {
+		vals := values[k]
+		column, op := parameterColumn(k, fieldMap)
+
+		if column == "" {
+			if strict {
+				return nil, fmt.Errorf("%w %q", errUnknownParameter, k)
+			}
+
+			continue
+		}
+
+		switch op {
+		case "", "eq", "ne", "in":
+			var val interface{} = vals
+
+			if op == "in" {
+				var items []string
+				for _, v := range vals {
+					items = append(items, strings.Split(v, ",")...)
+				}
+
+				val = items
+			} else if len(vals) == 1 {
+				val = vals[0]
+			}
+
+			if op == "ne" {
+				res = append(res, squirrel.NotEq{column: val})
+			} else {
+				res = append(res, squirrel.Eq{column: val})
+			}
+		default:
+			for _, v := range vals {
+				res = append(res, whereOperators[op](column, v))
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// parameterColumn finds column and operator of parameter name.
+func parameterColumn(name string, fieldMap map[string]string) (column string, op string) {
+	if column, ok := fieldMap[name]; ok {
+		return column, ""
+	}
+
+	p := strings.LastIndexByte(name, '_')
+	if p < 0 {
+		return "", ""
+	}
+
+	op = name[p+1:]
+
+	switch op {
+	case "eq", "ne", "gt", "ge", "lt", "le", "like", "ilike", "in":
+		return fieldMap[name[:p]], op
+	}
+
+	return "", ""
+}
@@ -0,0 +1,65 @@
+package sqluct_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/bool64/sqluct"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCond(t *testing.T) {
+	type User struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+		Role string `db:"role,omitempty"`
+	}
+
+	rf := sqluct.Referencer{}
+	u := &User{}
+	rf.AddTableAlias(u, "u")
+
+	sm := sqluct.Mapper{}
+	q := squirrel.Select("id").From("users u")
+
+	name := "Jo"
+	base := sqluct.Cond().When(true, rf.Eq(&u.ID, 1))
+	c := base.
+		When(name != "", squirrel.Like{rf.Ref(&u.Name): name + "%"}).
+		When(false, rf.Eq(&u.Role, "admin")).
+		When(true, sm.Where(User{}, sqluct.Columns("role"))).
+		When(true, sm.WhereEq(User{Role: "user"}, sqluct.Columns("role")))
+
+	assertStatementArgs(t, "SELECT id FROM users u WHERE (u.id = ? AND u.name LIKE ? AND role = ?)",
+		[]interface{}{1, "Jo%", "user"}, q.Where(c))
+	assertStatementArgs(t, "SELECT id FROM users u WHERE (u.id = ?)", []interface{}{1}, q.Where(base.And()))
+	assertStatementArgs(t, "SELECT id FROM users u WHERE (1=1)", nil, q.Where(sqluct.Cond()))
+	assert.Len(t, c.And(), 3)
+}
+
+func TestWhereFromValues(t *testing.T) {
+	fields := map[string]string{
+		"name":       "u.name",
+		"age":        "u.age",
+		"role":       "u.role",
+		"created_at": "u.created_at",
+	}
+
+	values, err := url.ParseQuery("name_like=J%25&age_gt=18&age_le=65&role_in=admin,owner&role=x&role=y" +
+		"&created_at_ge=2024-01-01&name_ne=John&page=2")
+	require.NoError(t, err)
+
+	cond, err := sqluct.WhereFromValues(values, fields, false)
+	require.NoError(t, err)
+
+	stmt, args, err := cond.ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "(u.age > ? AND u.age <= ? AND u.created_at >= ? AND u.name LIKE ? AND u.name <> ? "+
+		"AND u.role IN (?,?) AND u.role IN (?,?))", stmt)
+	assert.Equal(t, []interface{}{"18", "65", "2024-01-01", "J%", "John", "x", "y", "admin", "owner"}, args)
+
+	_, err = sqluct.WhereFromValues(values, fields, true)
+	require.EqualError(t, err, `unknown parameter "page"`)
+}
@@ -2,7 +2,9 @@ package sqluct
 
 import (
 	"context"
+	"sync"
 
+	"github.com/bool64/ctxd"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -22,3 +24,96 @@ func TxFromContext(ctx context.Context) *sqlx.Tx {
 
 	return tx
 }
+
+type labelCtxKey struct{}
+
+// LabelToContext adds logical operation label to context, label can be used in Storage.Trace for query attribution.
+//
+// Nested labels are appended to parent label with "/" separator, e.g. "checkout/reserve_stock".
+func LabelToContext(ctx context.Context, label string) context.Context {
+	if parent := LabelFromContext(ctx); parent != "" {
+		label = parent + "/" + label
+	}
+
+	return context.WithValue(ctx, labelCtxKey{}, label)
+}
+
+// LabelFromContext gets label or empty string from context.
+func LabelFromContext(ctx context.Context) string {
+	label, ok := ctx.Value(labelCtxKey{}).(string)
+	if !ok {
+		return ""
+	}
+
+	return label
+}
+
+type primaryCtxKey struct{}
+
+// ReadFromPrimary marks context to query primary database instead of Storage.Replica,
+// e.g. to read own writes right after they are made.
+func ReadFromPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryCtxKey{}, true)
+}
+
+func readsFromPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryCtxKey{}).(bool)
+
+	return primary
+}
+
+type txHooksCtxKey struct{}
+
+// txHooks holds callbacks of a transaction started with Storage.InTx.
+type txHooks struct {
+	mu         sync.Mutex
+	onCommit   []func(ctx context.Context) error
+	onRollback []func(ctx context.Context) error
+}
+
+func txHooksFromContext(ctx context.Context) *txHooks {
+	h, ok := ctx.Value(txHooksCtxKey{}).(*txHooks)
+	if !ok {
+		return nil
+	}
+
+	return h
+}
+
+// OnCommit registers a callback to run after transaction from context is committed.
+//
+// Callbacks run in registration order with a context that has no transaction,
+// their errors are returned from Storage.InTx (transaction stays committed).
+// Error is returned if there is no transaction started with Storage.InTx in context.
+func OnCommit(ctx context.Context, fn func(ctx context.Context) error) error {
+	h := txHooksFromContext(ctx)
+	if h == nil {
+		return ctxd.NewError(ctx, "no running transaction")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.onCommit = append(h.onCommit, fn)
+
+	return nil
+}
+
+// OnRollback registers a callback to run after transaction from context is rolled back or failed to commit.
+//
+// Callbacks run in registration order with a context that has no transaction,
+// their errors are reported to Storage.OnError and do not change result of Storage.InTx.
+// Error is returned if there is no transaction started with Storage.InTx in context.
+func OnRollback(ctx context.Context, fn func(ctx context.Context) error) error {
+	h := txHooksFromContext(ctx)
+	if h == nil {
+		return ctxd.NewError(ctx, "no running transaction")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.onRollback = append(h.onRollback, fn)
+
+	return nil
+}
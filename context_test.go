@@ -4,9 +4,11 @@ import (
 	"context"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/bool64/sqluct"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTxFromContext(t *testing.T) {
@@ -16,3 +18,36 @@ func TestTxFromContext(t *testing.T) {
 	ctx = sqluct.TxToContext(ctx, &tx)
 	assert.Equal(t, &tx, sqluct.TxFromContext(ctx))
 }
+
+func TestLabelFromContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "", sqluct.LabelFromContext(ctx))
+
+	ctx = sqluct.LabelToContext(ctx, "checkout")
+	assert.Equal(t, "checkout", sqluct.LabelFromContext(ctx))
+
+	child := sqluct.LabelToContext(ctx, "reserve_stock")
+	assert.Equal(t, "checkout/reserve_stock", sqluct.LabelFromContext(child))
+	assert.Equal(t, "checkout", sqluct.LabelFromContext(ctx))
+}
+
+func TestLabelFromContext_trace(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+	var label string
+
+	st.Trace = func(ctx context.Context, stmt string, args []interface{}) (newCtx context.Context, onFinish func(error)) {
+		label = sqluct.LabelFromContext(ctx)
+
+		return ctx, func(err error) {}
+	}
+
+	mock.ExpectExec("DELETE FROM table").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err = st.Exec(sqluct.LabelToContext(context.Background(), "cleanup"), st.DeleteStmt("table"))
+	require.NoError(t, err)
+	assert.Equal(t, "cleanup", label)
+}
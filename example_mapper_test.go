@@ -2,6 +2,7 @@ package sqluct_test
 
 import (
 	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/Masterminds/squirrel"
@@ -172,3 +173,58 @@ func ExampleMapper_WhereEq_columnsOf() {
 
 	// Output: SELECT orders.id, orders.amount, orders.user_id FROM orders JOIN users ON orders.user_id = users.id WHERE orders.amount = ? AND orders.user_id = ? [100 123] <nil>
 }
+
+func ExampleMapper_GroupBy() {
+	sm := sqluct.Mapper{}
+
+	type Order struct {
+		UserID int `db:"user_id"`
+		Amount int `db:"amount"`
+	}
+
+	rf := sqluct.Referencer{Mapper: &sm}
+
+	o := &Order{}
+	rf.AddTableAlias(o, "orders")
+
+	// Find users with orders total above 1000.
+	q := squirrel.Select(
+		rf.Ref(&o.UserID),
+		rf.Fmt("SUM(%s) AS total", &o.Amount),
+	).From(rf.Ref(o))
+
+	q = sm.GroupBy(q, o, rf.ColumnsOf(o), sqluct.Columns(rf.Col(&o.UserID))).
+		Having(rf.Fmt("SUM(%s) > ?", &o.Amount), 1000)
+
+	query, args, err := q.ToSql()
+	fmt.Println(query, args, err)
+
+	// Output: SELECT orders.user_id, SUM(orders.amount) AS total FROM orders GROUP BY orders.user_id HAVING SUM(orders.amount) > ? [1000] <nil>
+}
+
+// Decimal is a sample third-party decimal type with multiple zero representations.
+type Decimal struct {
+	Coef []byte
+	Exp  int
+}
+
+func ExampleMapper_RegisterZeroFunc() {
+	sm := sqluct.Mapper{}
+
+	sm.RegisterZeroFunc(Decimal{}, func(v reflect.Value) bool {
+		d := v.Interface().(Decimal) //nolint:errcheck // Type is guaranteed by registration.
+
+		return len(d.Coef) == 0 || (len(d.Coef) == 1 && d.Coef[0] == 0)
+	})
+
+	type Product struct {
+		ID    int     `db:"id"`
+		Price Decimal `db:"price,omitempty"`
+	}
+
+	q := sm.Update(squirrel.Update("products"), Product{ID: 1, Price: Decimal{Coef: []byte{0}, Exp: 2}})
+	query, args, err := q.ToSql()
+	fmt.Println(query, args, err)
+
+	// Output: UPDATE products SET id = ? [1] <nil>
+}
@@ -53,3 +53,66 @@ func ExampleReferencer_Fmt() {
 	// SELECT dr.manager_id, dr.employee_id FROM users AS manager INNER JOIN direct_reports AS dr ON dr.manager_id = manager.id AND dr.employee_id = employee.id WHERE manager.last_name = employee.last_name AND manager.first_name != ?
 	// [John]
 }
+
+func ExampleReferencer_WithCTE() {
+	type Order struct {
+		ID     int `db:"id"`
+		UserID int `db:"user_id"`
+		Amount int `db:"amount"`
+	}
+
+	type UserTotal struct {
+		UserID int `db:"user_id"`
+		Total  int `db:"total"`
+	}
+
+	rf := sqluct.Referencer{}
+
+	o := &Order{}
+	rf.AddTableAlias(o, "orders")
+
+	// CTE name becomes a table alias for UserTotal fields.
+	ut := &UserTotal{}
+	cte := rf.WithCTE(ut, "user_totals", squirrel.Select(
+		rf.Ref(&o.UserID),
+		rf.Fmt("SUM(%s) AS total", &o.Amount),
+	).From(rf.Ref(o)).GroupBy(rf.Ref(&o.UserID)))
+
+	// Find orders of users that have total amount above 1000.
+	qb := squirrel.Select(rf.Ref(&o.ID), rf.Ref(&ut.Total)).
+		PrefixExpr(cte).
+		From(rf.Ref(o)).
+		InnerJoin(rf.Fmt("%s ON %s = %s", ut, &ut.UserID, &o.UserID)).
+		Where(rf.Fmt("%s > ?", &ut.Total), 1000)
+
+	stmt, args, err := qb.ToSql()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(stmt)
+	fmt.Println(args)
+
+	// Output:
+	// WITH user_totals AS (SELECT orders.user_id, SUM(orders.amount) AS total FROM orders GROUP BY orders.user_id) SELECT orders.id, user_totals.total FROM orders INNER JOIN user_totals ON user_totals.user_id = orders.user_id WHERE user_totals.total > ?
+	// [1000]
+}
+
+func ExampleReferencer_Fmt_literals() {
+	type Order struct {
+		ID     int `db:"id"`
+		Amount int `db:"amount"`
+	}
+
+	rf := sqluct.Referencer{}
+
+	o := &Order{}
+	rf.AddTableAlias(o, "orders")
+
+	// Field pointers are resolved to references, other arguments are formatted as is.
+	fmt.Println(rf.Fmt("SELECT %s FROM %s WHERE %s > %d ORDER BY %s LIMIT %d",
+		&o.ID, o, &o.Amount, 100, &o.ID, 10))
+
+	// Output:
+	// SELECT orders.id FROM orders WHERE orders.amount > 100 ORDER BY orders.id LIMIT 10
+}
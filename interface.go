@@ -0,0 +1,41 @@
+package sqluct
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+// Execer executes statements in optional transaction, it is implemented by *Storage.
+type Execer interface {
+	Exec(ctx context.Context, qb ToSQL) (sql.Result, error)
+	InTx(ctx context.Context, fn func(context.Context) error) error
+}
+
+// Selector queries statements, it is implemented by *Storage.
+type Selector interface {
+	Query(ctx context.Context, qb ToSQL) (*sqlx.Rows, error)
+	Select(ctx context.Context, qb ToSQL, dest interface{}) error
+}
+
+// StmtBuilder makes statement builders, it is implemented by *Storage.
+type StmtBuilder interface {
+	QueryBuilder() squirrel.StatementBuilderType
+	SelectStmt(tableName string, columns interface{}, options ...func(*Options)) squirrel.SelectBuilder
+	InsertStmt(tableName string, val interface{}, options ...func(*Options)) squirrel.InsertBuilder
+	UpdateStmt(tableName string, val interface{}, options ...func(*Options)) squirrel.UpdateBuilder
+	DeleteStmt(tableName string) squirrel.DeleteBuilder
+}
+
+// Storer is a database storage, it is implemented by *Storage.
+//
+// Consumers can depend on Storer (or on a narrower interface) to inject fakes in tests.
+type Storer interface {
+	Execer
+	Selector
+	StmtBuilder
+}
+
+var _ Storer = &Storage{}
@@ -0,0 +1,48 @@
+package sqluct
+
+import (
+	"context"
+	"time"
+
+	"github.com/bool64/ctxd"
+)
+
+// QueryInfo describes finished database call.
+type QueryInfo struct {
+	// SQL is a statement with comment from Storage.CommentFromContext.
+	SQL string
+
+	// Args are statement arguments, Storage.RedactArgs is applied to them.
+	Args []interface{}
+
+	Duration time.Duration
+	Err      error
+}
+
+// Logger receives information about finished database calls of Storage.
+type Logger interface {
+	Log(ctx context.Context, q QueryInfo)
+}
+
+// SlowQueryLogger is a Logger that warns about statements that took at least Threshold to finish.
+//
+// It does nothing if Logger is nil.
+type SlowQueryLogger struct {
+	Logger    ctxd.Logger
+	Threshold time.Duration
+}
+
+// Log implements Logger.
+func (l SlowQueryLogger) Log(ctx context.Context, q QueryInfo) {
+	if l.Logger == nil || q.Duration < l.Threshold {
+		return
+	}
+
+	kv := []interface{}{"sql", q.SQL, "args", q.Args, "duration", q.Duration.String()}
+
+	if q.Err != nil {
+		kv = append(kv, "error", q.Err.Error())
+	}
+
+	l.Logger.Warn(ctx, "slow query", kv...)
+}
@@ -0,0 +1,78 @@
+package sqluct_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/Masterminds/squirrel"
+	"github.com/bool64/ctxd"
+	"github.com/bool64/sqluct"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type loggerFunc func(ctx context.Context, q sqluct.QueryInfo)
+
+func (f loggerFunc) Log(ctx context.Context, q sqluct.QueryInfo) {
+	f(ctx, q)
+}
+
+func TestStorage_Logger(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	st.RedactArgs = sqluct.RedactArgsByIndex(0)
+
+	var logged []sqluct.QueryInfo
+
+	st.Logger = loggerFunc(func(_ context.Context, q sqluct.QueryInfo) {
+		logged = append(logged, q)
+	})
+
+	mock.ExpectExec("DELETE FROM users WHERE id = $1").
+		WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err = st.Exec(context.Background(), st.DeleteStmt("users").Where(squirrel.Eq{"id": 1}))
+	require.NoError(t, err)
+
+	mock.ExpectQuery("SELECT id FROM users").WillReturnError(errors.New("failed"))
+
+	var ids []int
+
+	require.Error(t, st.Select(context.Background(), st.QueryBuilder().Select("id").From("users"), &ids))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, logged, 2)
+	assert.Equal(t, "DELETE FROM users WHERE id = $1", logged[0].SQL)
+	assert.Equal(t, []interface{}{sqluct.Redacted}, logged[0].Args)
+	assert.NoError(t, logged[0].Err)
+	assert.Greater(t, logged[0].Duration, time.Duration(0))
+	assert.Equal(t, "SELECT id FROM users", logged[1].SQL)
+	assert.EqualError(t, logged[1].Err, "failed")
+}
+
+func TestSlowQueryLogger_Log(t *testing.T) {
+	lm := &ctxd.LoggerMock{}
+	l := sqluct.SlowQueryLogger{Logger: lm, Threshold: time.Second}
+	ctx := context.Background()
+
+	l.Log(ctx, sqluct.QueryInfo{SQL: "SELECT 1", Duration: time.Millisecond})
+	l.Log(ctx, sqluct.QueryInfo{SQL: "SELECT 2", Args: []interface{}{1}, Duration: 2 * time.Second})
+	l.Log(ctx, sqluct.QueryInfo{SQL: "SELECT 3", Duration: time.Second, Err: errors.New("failed")})
+
+	assert.Equal(t, `warn: slow query {"args":[1],"duration":"2s","sql":"SELECT 2"}`+"\n"+
+		`warn: slow query {"args":null,"duration":"1s","error":"failed","sql":"SELECT 3"}`+"\n", lm.String())
+}
+
+func TestSlowQueryLogger_Log_nilLogger(t *testing.T) {
+	l := sqluct.SlowQueryLogger{Threshold: time.Second}
+
+	assert.NotPanics(t, func() {
+		l.Log(context.Background(), sqluct.QueryInfo{SQL: "SELECT 1", Duration: 2 * time.Second})
+	})
+}
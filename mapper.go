@@ -1,28 +1,130 @@
 package sqluct
 
 import (
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx/reflectx"
 )
 
 var (
+	sqlDefault = squirrel.Expr("DEFAULT")
+
 	errUnknownFieldOrRow = errors.New("unknown field or row or not a pointer")
 	errNotAPointer       = errors.New("can not take address of structure, please pass a pointer")
 	errNilArgument       = errors.New("structPtr and fieldPtr are required")
+	errUnknownColumn     = errors.New("unknown column")
+	errDuplicateColumn   = errors.New("duplicate column")
+	errNilItem           = errors.New("nil item in slice")
+	errUnknownTagOption  = errors.New("unknown tag option")
+)
+
+// Field tag options that control usage of columns in statements.
+const (
+	// ReadOnly is the name of field tag option to exclude column from INSERT and UPDATE statements.
+	ReadOnly = "readonly"
+
+	// InsertOnly is the name of field tag option to exclude column from UPDATE statements.
+	InsertOnly = "insertOnly"
+
+	// Auto is the name of field tag option to exclude database-generated column from INSERT statements
+	// regardless of field value, e.g. `db:"id,auto"` for serial ID or `db:"created_at,auto"` for defaulted timestamp.
+	Auto = "auto"
+
+	// Inline is the name of field tag option to map fields of a named structure as columns
+	// prefixed with structure field name, e.g. `db:"addr,inline"` maps to `addr_street`, `addr_city`.
+	Inline = "inline"
+
+	// Default is the name of field tag option to insert a default value instead of skipped zero value,
+	// e.g. `db:"status,omitempty,default=new"`. Default value is a bind argument of string type.
+	Default = "default"
+)
+
+type statementType int
+
+const (
+	statementOther statementType = iota
+	statementInsert
+	statementUpdate
 )
 
 // Mapper prepares select, insert and update statements.
+//
+// Fields without tags and fields with `db:"-"` tag are not mapped to columns.
+//
+// Mapper caches field mapping for every seen structure type, the cache grows with number of types
+// (including anonymous types of ad-hoc conditions). Use MaxCachedTypes or ClearCache to limit the growth.
+// Nil Mapper uses a shared default instance.
 type Mapper struct {
 	ReflectMapper *reflectx.Mapper
 	Dialect       Dialect
 
+	// ColumnNameMapper transforms column names resolved from field tags, for example strings.ToUpper.
+	// It is applied to all generated and referenced columns before quoting.
+	ColumnNameMapper func(string) string
+
+	// MaxCachedTypes limits number of cached structure types, cache is cleared when limit is reached.
+	// Default 0 means no limit.
+	MaxCachedTypes int
+
+	// mapUntagged enables mapping of fields without tags, see NewMapperFunc.
+	mapUntagged bool
+
 	mu    sync.Mutex
 	types map[reflect.Type]*reflectx.StructMap
+
+	// zeroFuncs holds map[reflect.Type]func(reflect.Value) bool, it is replaced on registration.
+	zeroFuncs atomic.Value
+}
+
+// RegisterZeroFunc registers emptiness check for type of sample value.
+//
+// The check is used by SkipZeroValues and `omitempty` instead of comparison with zero value of the type,
+// for example for types that have multiple empty representations or are not comparable.
+//
+//	sm.RegisterZeroFunc(decimal.Decimal{}, func(v reflect.Value) bool {
+//		return v.Interface().(decimal.Decimal).IsZero()
+//	})
+func (sm *Mapper) RegisterZeroFunc(sample interface{}, fn func(v reflect.Value) bool) {
+	if sm == nil {
+		sm = defaultMapper
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	prev, _ := sm.zeroFuncs.Load().(map[reflect.Type]func(reflect.Value) bool)
+	funcs := make(map[reflect.Type]func(reflect.Value) bool, len(prev)+1)
+
+	for t, f := range prev {
+		funcs[t] = f
+	}
+
+	funcs[reflect.TypeOf(sample)] = fn
+
+	sm.zeroFuncs.Store(funcs)
+}
+
+// isZero checks if field value is empty with registered or default check.
+func (sm *Mapper) isZero(colV reflect.Value, val interface{}) bool {
+	if sm == nil {
+		sm = defaultMapper
+	}
+
+	if funcs, ok := sm.zeroFuncs.Load().(map[reflect.Type]func(reflect.Value) bool); ok {
+		if fn, ok := funcs[colV.Type()]; ok {
+			return fn(colV)
+		}
+	}
+
+	return isZero(colV, val)
 }
 
 var (
@@ -30,6 +132,23 @@ var (
 	defaultMapper = &Mapper{}
 )
 
+// NewMapperFunc creates Mapper that also maps fields without tags, column names of such fields
+// are made from field names with mapFunc.
+//
+// Tag (e.g. "db") still defines column names and options of tagged fields, `db:"-"` skips a field.
+//
+//	sm := sqluct.NewMapperFunc("db", toSnakeCase) // CreatedAt time.Time -> created_at
+//
+// Database mapper of sqlx should be configured with same ReflectMapper for scanning.
+//
+//	st.DB().Mapper = sm.ReflectMapper
+func NewMapperFunc(tag string, mapFunc func(string) string) *Mapper {
+	return &Mapper{
+		ReflectMapper: reflectx.NewMapperFunc(tag, mapFunc),
+		mapUntagged:   true,
+	}
+}
+
 // SkipZeroValues instructs mapper to ignore fields with zero values.
 func SkipZeroValues(o *Options) {
 	o.SkipZeroValues = true
@@ -40,11 +159,55 @@ func IgnoreOmitEmpty(o *Options) {
 	o.IgnoreOmitEmpty = true
 }
 
+// ZeroTimeIsValid instructs mapper to keep zero time.Time values regardless of `omitempty` and SkipZeroValues.
+func ZeroTimeIsValid(o *Options) {
+	o.NeverZero = append(o.NeverZero, reflect.TypeOf(time.Time{}))
+}
+
+// NeverZero instructs mapper to keep zero values of types of provided samples
+// regardless of `omitempty` and SkipZeroValues.
+//
+//	sqluct.NeverZero(time.Time{}, decimal.Decimal{})
+func NeverZero(samples ...interface{}) func(o *Options) {
+	types := make([]reflect.Type, 0, len(samples))
+	for _, s := range samples {
+		types = append(types, reflect.TypeOf(s))
+	}
+
+	return func(o *Options) {
+		o.NeverZero = append(o.NeverZero, types...)
+	}
+}
+
 // InsertIgnore enables ignoring of row conflict during INSERT.
 func InsertIgnore(o *Options) {
 	o.InsertIgnore = true
 }
 
+// ForUpdate adds FOR UPDATE row locking clause to SELECT.
+//
+// Row locking is supported by Postgres and MySQL 8, mapper panics for other dialects.
+func ForUpdate(o *Options) {
+	o.RowLock = "UPDATE"
+}
+
+// ForShare adds FOR SHARE row locking clause to SELECT.
+//
+// Row locking is supported by Postgres and MySQL 8, mapper panics for other dialects.
+func ForShare(o *Options) {
+	o.RowLock = "SHARE"
+}
+
+// SkipLocked adds SKIP LOCKED to row locking clause of SELECT, FOR UPDATE is implied if no lock is set.
+func SkipLocked(o *Options) {
+	o.RowLockWait = "SKIP LOCKED"
+}
+
+// NoWait adds NOWAIT to row locking clause of SELECT, FOR UPDATE is implied if no lock is set.
+func NoWait(o *Options) {
+	o.RowLockWait = "NOWAIT"
+}
+
 // Columns are used to control which columns from the structure should be used.
 func Columns(columns ...string) func(o *Options) {
 	return func(o *Options) {
@@ -52,11 +215,56 @@ func Columns(columns ...string) func(o *Options) {
 	}
 }
 
-// OrderDesc instructs mapper to use DESC order in Product func.
+// ExtraColumns adds raw column expressions after structure columns in SELECT.
+//
+// Expressions are not quoted, e.g. ExtraColumns("now() - created_at AS age").
+func ExtraColumns(columns ...string) func(o *Options) {
+	return func(o *Options) {
+		o.ExtraColumns = append(o.ExtraColumns, columns...)
+	}
+}
+
+// QualifyColumns instructs Storage.SelectStmt to prefix generated columns with table name,
+// e.g. `"users"."id"` instead of `"id"`.
+//
+// It has no effect if PrepareColumn is already set, for example by Referencer.ColumnsOf.
+func QualifyColumns(o *Options) {
+	o.QualifyColumns = true
+}
+
+// UseAnyArray instructs mapper to use `col = ANY(?)` with a single array argument
+// for slice values in conditions of Postgres dialect.
+//
+// For other dialects `col IN (?,?,...)` is used.
+func UseAnyArray(o *Options) {
+	o.UseAnyArray = true
+}
+
+// OrderDesc instructs mapper to use DESC order in OrderBy.
 func OrderDesc(o *Options) {
 	o.OrderDesc = true
 }
 
+// NullsFirst instructs mapper to put NULL values first when ordering by provided columns in OrderBy.
+//
+// NULLS FIRST clause is used for Postgres and SQLite, for other dialects (e.g. MySQL)
+// it is emulated with `col IS NULL DESC` ordering.
+func NullsFirst(columns ...string) func(o *Options) {
+	return func(o *Options) {
+		o.NullsFirst = append(o.NullsFirst, columns...)
+	}
+}
+
+// NullsLast instructs mapper to put NULL values last when ordering by provided columns in OrderBy.
+//
+// NULLS LAST clause is used for Postgres and SQLite, for other dialects (e.g. MySQL)
+// it is emulated with `col IS NULL` ordering.
+func NullsLast(columns ...string) func(o *Options) {
+	return func(o *Options) {
+		o.NullsLast = append(o.NullsLast, columns...)
+	}
+}
+
 // Options defines mapping and query building parameters.
 type Options struct {
 	// SkipZeroValues instructs mapper to ignore fields with zero values regardless of `omitempty` tag.
@@ -65,21 +273,65 @@ type Options struct {
 	// IgnoreOmitEmpty instructs mapper to use zero values of fields with `omitempty`.
 	IgnoreOmitEmpty bool
 
+	// NeverZero is a list of types which values are never skipped as zero.
+	NeverZero []reflect.Type
+
 	// Columns is used to control which columns from the structure should be used.
 	Columns []string
 
-	// OrderDesc instructs mapper to use DESC order in Product func.
+	// ExtraColumns are raw column expressions that are added after structure columns in SELECT.
+	ExtraColumns []string
+
+	// OrderDesc instructs mapper to use DESC order in OrderBy.
 	OrderDesc bool
 
+	// NullsFirst is a list of columns that should have NULL values first in OrderBy.
+	NullsFirst []string
+
+	// NullsLast is a list of columns that should have NULL values last in OrderBy.
+	NullsLast []string
+
 	// PrepareColumn allows control of column quotation or aliasing.
 	PrepareColumn func(col string) string
 
+	// QualifyColumns instructs Storage.SelectStmt to prefix generated columns with table name.
+	QualifyColumns bool
+
 	// InsertIgnore enables ignoring of row conflict during INSERT.
 	// Uses
 	//  - INSERT IGNORE for MySQL,
 	//  - INSERT OR IGNORE for SQLite3,
 	//  - INSERT ... ON CONFLICT DO NOTHING for Postgres.
 	InsertIgnore bool
+
+	// OnConflict is an ON CONFLICT clause of INSERT, e.g. made with Referencer.OnConflict.
+	// Supported by Postgres and SQLite3, mapper panics for MySQL.
+	OnConflict squirrel.Sqlizer
+
+	// RowLock is a row locking strength of SELECT, "UPDATE" or "SHARE" rendered as FOR UPDATE or FOR SHARE.
+	// Supported by Postgres and MySQL 8, mapper panics for other dialects.
+	RowLock string
+
+	// RowLockWait is a waiting policy of row locking, "SKIP LOCKED" or "NOWAIT".
+	RowLockWait string
+
+	// UseAnyArray enables `col = ANY(?)` conditions with array argument instead of `col IN (?,?,...)`
+	// for slice values in Postgres dialect.
+	UseAnyArray bool
+
+	// RenameColumns maps column names defined by field tags to column names used in statement.
+	// Renaming is applied after filtering with Columns and before PrepareColumn.
+	RenameColumns map[string]string
+
+	// UseDefault is a list of columns that should have DEFAULT keyword instead of field value.
+	// DEFAULT in VALUES is supported by MySQL and Postgres.
+	UseDefault []string
+
+	// ValueExprs maps columns to SQL expressions that are inserted instead of field values.
+	ValueExprs map[string]string
+
+	// statement is a type of statement being built.
+	statement statementType
 }
 
 // Insert adds struct value or slice of struct values to squirrel.InsertBuilder.
@@ -95,9 +347,11 @@ func (sm *Mapper) Insert(q squirrel.InsertBuilder, val interface{}, options ...f
 		option(&o)
 	}
 
+	o.statement = statementInsert
+
 	if o.InsertIgnore {
 		switch sm.Dialect {
-		case DialectMySQL:
+		case DialectMySQL, DialectMariaDB:
 			q = q.Options("IGNORE")
 		case DialectSQLite3:
 			q = q.Options("OR IGNORE")
@@ -110,14 +364,26 @@ func (sm *Mapper) Insert(q squirrel.InsertBuilder, val interface{}, options ...f
 		}
 	}
 
+	if o.OnConflict != nil {
+		if isMySQL(sm.Dialect) {
+			panic(fmt.Sprintf("can not apply ON CONFLICT for dialect %q", sm.Dialect))
+		}
+
+		q = q.SuffixExpr(o.OnConflict)
+	}
+
 	if v.Kind() == reflect.Slice {
 		return sm.sliceInsert(q, v, o)
 	}
 
-	cols, vals := sm.columnsValues(v, o)
+	buf := columnsPool.Get().(*[]string) //nolint:errcheck // Pool only contains *[]string.
+	cols, vals, _ := sm.appendColumnsValuesFields((*buf)[:0], v, o, false)
 	q = q.Columns(cols...)
 	q = q.Values(vals...)
 
+	*buf = cols
+	columnsPool.Put(buf)
+
 	return q
 }
 
@@ -128,9 +394,18 @@ func (sm *Mapper) sliceInsert(q squirrel.InsertBuilder, v reflect.Value, o Optio
 		qq            = q
 	)
 
+	buf := columnsPool.Get().(*[]string) //nolint:errcheck // Pool only contains *[]string.
+	defer columnsPool.Put(buf)
+
 	for i := 0; i < v.Len(); i++ {
 		item := v.Index(i)
-		cols, vals := sm.columnsValues(item, o)
+
+		if item.Kind() == reflect.Ptr && item.IsNil() {
+			return q.Values(errStmt{err: fmt.Errorf("%w at index %d", errNilItem, i)})
+		}
+
+		cols, vals, _ := sm.appendColumnsValuesFields((*buf)[:0], item, o, false)
+		*buf = cols
 
 		if i == 0 {
 			for _, c := range cols {
@@ -139,6 +414,11 @@ func (sm *Mapper) sliceInsert(q squirrel.InsertBuilder, v reflect.Value, o Optio
 
 			qq = qq.Columns(cols...)
 		} else {
+			// Row with fewer columns.
+			if len(cols) != len(hCols) {
+				heterogeneous = true
+			}
+
 			for _, c := range cols {
 				if _, found := hCols[c]; !found {
 					heterogeneous = true
@@ -159,14 +439,21 @@ func (sm *Mapper) sliceInsert(q squirrel.InsertBuilder, v reflect.Value, o Optio
 	return qq
 }
 
+// heterogeneousInsert inserts rows with union of their columns, columns follow order of struct fields.
 func (sm *Mapper) heterogeneousInsert(q squirrel.InsertBuilder, v reflect.Value, hCols map[string]struct{}, o Options) squirrel.InsertBuilder {
+	o.SkipZeroValues = false
+	o.IgnoreOmitEmpty = true
+
+	// Collecting names of used columns as defined in field tags to filter with Options.Columns.
+	all, _, fields := sm.columnsValuesFields(v, o, true)
 	cols := make([]string, 0, len(hCols))
-	for c := range hCols {
-		cols = append(cols, c)
+
+	for i, c := range all {
+		if _, ok := hCols[c]; ok {
+			cols = append(cols, sm.fieldColName(fields[i]))
+		}
 	}
 
-	o.SkipZeroValues = false
-	o.IgnoreOmitEmpty = true
 	o.Columns = cols
 
 	for i := 0; i < v.Len(); i++ {
@@ -195,6 +482,8 @@ func (sm *Mapper) Update(q squirrel.UpdateBuilder, val interface{}, options ...f
 		option(&o)
 	}
 
+	o.statement = statementUpdate
+
 	cols, vals := sm.columnsValues(reflect.ValueOf(val), o)
 	for i, col := range cols {
 		q = q.Set(col, vals[i])
@@ -215,148 +504,852 @@ func (sm *Mapper) Select(q squirrel.SelectBuilder, columns interface{}, options
 		option(&o)
 	}
 
-	o.IgnoreOmitEmpty = true
+	q = q.Columns(sm.selectColumns(columns, o)...)
 
-	cols, _ := sm.columnsValues(reflect.ValueOf(columns), o)
-	q = q.Columns(cols...)
+	if len(o.ExtraColumns) > 0 {
+		q = q.Columns(o.ExtraColumns...)
+	}
+
+	if o.RowLock != "" || o.RowLockWait != "" {
+		q = q.Suffix(sm.rowLock(o))
+	}
 
 	return q
 }
 
-// WhereEq maps struct values as conditions to squirrel.Eq.
-func (sm *Mapper) WhereEq(conditions interface{}, options ...func(*Options)) squirrel.Eq {
-	o := Options{}
+// selectColumns returns structure columns for SELECT statement.
+func (sm *Mapper) selectColumns(columns interface{}, o Options) []string {
+	o.SkipZeroValues = false
+	o.IgnoreOmitEmpty = true
 
-	for _, option := range options {
-		option(&o)
+	cols, _, fields := sm.columnsValuesFields(reflect.ValueOf(columns), o, true)
+
+	for i, fi := range fields {
+		// Columns of inline structure and renamed columns are aliased with field path for scanning.
+		if _, renamed := o.RenameColumns[sm.fieldColName(fi)]; renamed || isInlined(fi) {
+			cols[i] += " AS " + sm.quoteAlias(fi.Path)
+		}
 	}
 
-	columns, values := sm.columnsValues(reflect.ValueOf(conditions), o)
-	eq := make(squirrel.Eq, len(columns))
+	return cols
+}
 
-	for i, column := range columns {
-		eq[column] = values[i]
+// rowLock renders row locking clause.
+func (sm *Mapper) rowLock(o Options) string {
+	switch sm.Dialect {
+	case DialectPostgres, DialectMySQL, DialectMariaDB:
+	case DialectUnknown:
+		panic("can not apply row locking for unknown dialect")
+	default:
+		panic(fmt.Sprintf("can not apply row locking for dialect %q", sm.Dialect))
 	}
 
-	if len(eq) == 0 {
-		return nil
+	lock := "FOR " + o.RowLock
+	if o.RowLock == "" {
+		lock = "FOR UPDATE"
 	}
 
-	return eq
+	if o.RowLockWait != "" {
+		lock += " " + o.RowLockWait
+	}
+
+	return lock
 }
 
-func (sm *Mapper) colType(v reflect.Value) (*reflectx.StructMap, bool) {
-	v = reflect.Indirect(v)
-	k := v.Kind()
-	t := v.Type()
-	skipValues := false
+// ColumnsString returns comma-separated columns of a structure, e.g. "a, b, c".
+//
+// Use ColumnsOf option of Referencer to prefix columns with table alias,
+// it can be useful for hand-written statements like INSERT ... SELECT.
+func (sm *Mapper) ColumnsString(v interface{}, options ...func(*Options)) string {
+	o := Options{}
 
-	if k == reflect.Slice || k == reflect.Array {
-		t = t.Elem()
-		k = t.Kind()
-		skipValues = true
+	for _, option := range options {
+		option(&o)
 	}
 
-	if k != reflect.Struct {
-		panic("struct or slice/array of struct expected in sql query mapper")
-	}
+	o.SkipZeroValues = false
+	o.IgnoreOmitEmpty = true
 
-	tm := sm.typeMap(t)
+	cols, _ := sm.columnsValues(reflect.ValueOf(v), o)
 
-	return tm, skipValues
+	return strings.Join(cols, ", ")
 }
 
-func (sm *Mapper) skip(fi *reflectx.FieldInfo, columns []string) bool {
-	if fi.Embedded {
-		return true
+// ValuesLiteral maps struct value or slice of struct values to a VALUES table literal with `?` placeholders.
+//
+// Literal is aliased as a table with column names, so it can be used in FROM or JOIN, e.g.
+// `(VALUES (?,?),(?,?)) AS t(id, name)` for Postgres, `(VALUES ROW(?,?),ROW(?,?)) AS t(id, name)` for MySQL 8,
+// `(SELECT ? AS id, ? AS name UNION ALL SELECT ?, ?) AS t` for SQLite and MariaDB that do not support
+// column aliases.
+//
+//	lit, args := sm.ValuesLiteral(items, "t", "id", "name")
+//	q = q.JoinClause("JOIN "+lit+" ON t.id = items.id", args...)
+//
+// Columns limit the mapped columns, all columns are mapped if none provided.
+// Nil pointers in slice are skipped, empty string is returned if there are no rows.
+func (sm *Mapper) ValuesLiteral(rows interface{}, alias string, columns ...string) (string, []interface{}) {
+	v := reflect.Indirect(reflect.ValueOf(rows))
+	o := Options{Columns: columns, IgnoreOmitEmpty: true}
+
+	if v.Kind() != reflect.Slice {
+		v = reflect.Append(reflect.MakeSlice(reflect.SliceOf(v.Type()), 0, 1), v)
 	}
 
-	if fi.Field.Tag == "" {
-		return true
+	var (
+		cols []string
+		args []interface{}
+		res  strings.Builder
+		n    int
+	)
+
+	selectUnion := sm.Dialect == DialectSQLite3 || sm.Dialect == DialectMariaDB
+
+	if selectUnion {
+		res.WriteString("(SELECT ")
+	} else {
+		res.WriteString("(VALUES ")
 	}
 
-	if len(columns) > 0 {
-		pick := false
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i)
+		if item.Kind() == reflect.Ptr && item.IsNil() {
+			continue
+		}
+
+		c, vals := sm.columnsValues(reflect.Indirect(item), o)
+		if n == 0 {
+			cols = c
+		}
 
-		for _, col := range columns {
-			if col == fi.Name {
-				pick = true
+		args = append(args, vals...)
 
-				break
+		switch {
+		case selectUnion:
+			if n > 0 {
+				res.WriteString(" UNION ALL SELECT ")
 			}
-		}
 
-		if !pick {
-			return true
+			for j := range vals {
+				if j > 0 {
+					res.WriteString(", ")
+				}
+
+				res.WriteString("?")
+
+				if n == 0 {
+					res.WriteString(" AS " + cols[j])
+				}
+			}
+
+			n++
+
+			continue
+		case sm.Dialect == DialectMySQL:
+			if n > 0 {
+				res.WriteString(",")
+			}
+
+			res.WriteString("ROW")
+		default:
+			if n > 0 {
+				res.WriteString(",")
+			}
 		}
+
+		n++
+
+		res.WriteString("(" + strings.Repeat(",?", len(vals))[1:] + ")")
 	}
 
-	return false
-}
+	if n == 0 {
+		return "", nil
+	}
 
-func isZero(colV reflect.Value, val interface{}) bool {
-	k := colV.Kind()
-	if k == reflect.Slice || k == reflect.Map {
-		if colV.Len() == 0 {
-			return true
-		}
-	} else {
-		if val == nil || val == reflect.Zero(colV.Type()).Interface() {
-			return true
-		}
+	res.WriteString(") AS " + alias)
+
+	if !selectUnion {
+		res.WriteString("(" + strings.Join(cols, ", ") + ")")
 	}
 
-	return false
+	return res.String(), args
 }
 
-// ColumnsValues extracts columns and values from provided struct value.
-func (sm *Mapper) ColumnsValues(v reflect.Value, options ...func(*Options)) ([]string, []interface{}) {
+// GroupBy maps struct field tags as GROUP BY columns to squirrel.SelectBuilder.
+//
+// Use Columns option to group by a subset of fields.
+func (sm *Mapper) GroupBy(q squirrel.SelectBuilder, columns interface{}, options ...func(*Options)) squirrel.SelectBuilder {
+	if columns == nil {
+		return q
+	}
+
 	o := Options{}
 
 	for _, option := range options {
 		option(&o)
 	}
 
-	return sm.columnsValues(v, o)
+	o.SkipZeroValues = false
+	o.IgnoreOmitEmpty = true
+
+	cols, _ := sm.columnsValues(reflect.ValueOf(columns), o)
+
+	return q.GroupBy(cols...)
 }
 
-func (sm *Mapper) columnsValues(v reflect.Value, o Options) ([]string, []interface{}) {
-	tm, skipValues := sm.colType(v)
-	columns := make([]string, 0, len(tm.Index))
-	values := make([]interface{}, 0, len(tm.Index))
+// OrderBy maps struct field tags as ORDER BY columns to squirrel.SelectBuilder.
+//
+// Use Columns option to order by a subset of fields, OrderDesc for descending order and
+// NullsFirst or NullsLast to control position of NULL values.
+func (sm *Mapper) OrderBy(q squirrel.SelectBuilder, columns interface{}, options ...func(*Options)) squirrel.SelectBuilder {
+	if columns == nil {
+		return q
+	}
 
-	for _, fi := range tm.Index {
-		if sm.skip(fi, o.Columns) {
-			continue
-		}
+	o := Options{}
 
-		if !skipValues {
-			colV := reflectx.FieldByIndexesReadOnly(v, fi.Index)
-			val := colV.Interface()
+	for _, option := range options {
+		option(&o)
+	}
 
-			_, omitEmpty := fi.Options["omitempty"]
+	o.SkipZeroValues = false
+	o.IgnoreOmitEmpty = true
+	prepare := o.PrepareColumn
+	o.PrepareColumn = nil
 
-			if o.IgnoreOmitEmpty && omitEmpty {
-				omitEmpty = false
-			}
+	cols, _ := sm.columnsValues(reflect.ValueOf(columns), o)
+	orderBys := make([]string, 0, len(cols))
 
-			if (o.SkipZeroValues || omitEmpty) && isZero(colV, val) {
-				continue
-			}
+	o.PrepareColumn = prepare
 
-			values = append(values, val)
+	for _, name := range cols {
+		col := o.prepareColumn(name)
+		dir := ""
+
+		if o.OrderDesc {
+			dir = " DESC"
 		}
 
-		if o.PrepareColumn != nil {
-			columns = append(columns, o.PrepareColumn(fi.Name))
-		} else {
-			columns = append(columns, fi.Name)
+		nulls := ""
+
+		switch {
+		case hasColumn(o.NullsFirst, name):
+			nulls = "FIRST"
+		case hasColumn(o.NullsLast, name):
+			nulls = "LAST"
+		}
+
+		switch {
+		case nulls == "":
+			orderBys = append(orderBys, col+dir)
+		case sm.Dialect == DialectPostgres || sm.Dialect == DialectSQLite3:
+			orderBys = append(orderBys, col+dir+" NULLS "+nulls)
+		case nulls == "FIRST":
+			orderBys = append(orderBys, col+" IS NULL DESC", col+dir)
+		default:
+			orderBys = append(orderBys, col+" IS NULL", col+dir)
 		}
 	}
 
+	return q.OrderBy(orderBys...)
+}
+
+// WhereEq maps struct values as conditions to squirrel.Eq.
+//
+// Please use Where for conditions with raw expressions (Quoted or squirrel.Sqlizer values)
+// or operators, squirrel.Eq can only have bind arguments.
+func (sm *Mapper) WhereEq(conditions interface{}, options ...func(*Options)) squirrel.Eq {
+	o := Options{}
+
+	for _, option := range options {
+		option(&o)
+	}
+
+	columns, values := sm.columnsValues(reflect.ValueOf(conditions), o)
+	eq := make(squirrel.Eq, len(columns))
+
+	for i, column := range columns {
+		eq[column] = values[i]
+	}
+
+	if len(eq) == 0 {
+		return nil
+	}
+
+	return eq
+}
+
+// WhereEqOrdered maps struct values as equality conditions to squirrel.And in column order of mapper.
+//
+// Unlike WhereEq, order of conditions follows order of structure fields instead of sorted column names.
+// Nil is returned if there are no conditions, so that it is ignored by squirrel.SelectBuilder.Where.
+func (sm *Mapper) WhereEqOrdered(conditions interface{}, options ...func(*Options)) squirrel.Sqlizer {
+	o := Options{}
+
+	for _, option := range options {
+		option(&o)
+	}
+
+	columns, values := sm.columnsValues(reflect.ValueOf(conditions), o)
+	if len(columns) == 0 {
+		return nil
+	}
+
+	and := make(squirrel.And, 0, len(columns))
+
+	for i, column := range columns {
+		and = append(and, sm.eq(column, values[i], o))
+	}
+
+	return and
+}
+
+// WhereEqAny maps slice of struct values as alternative conditions to squirrel.Or of squirrel.And groups.
+//
+// Empty slice results in always false condition.
+func (sm *Mapper) WhereEqAny(conditions interface{}, options ...func(*Options)) squirrel.Or {
+	o := Options{}
+
+	for _, option := range options {
+		option(&o)
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(conditions))
+	if k := v.Kind(); k != reflect.Slice && k != reflect.Array {
+		panic("slice/array of struct expected in sql query mapper")
+	}
+
+	or := make(squirrel.Or, 0, v.Len())
+
+	for i := 0; i < v.Len(); i++ {
+		columns, values, fields := sm.columnsValuesFields(v.Index(i), o, true)
+		or = append(or, sm.and(columns, values, fields, o))
+	}
+
+	return or
+}
+
+// Where maps struct values as conditions to squirrel.Sqlizer.
+//
+// Unlike WhereEq, conditions follow order of struct fields and UseAnyArray option is supported.
+// It returns nil if there are no conditions.
+//
+// Values of Quoted or squirrel.Sqlizer type are used as raw expressions instead of bind arguments,
+// e.g. to compare columns.
+//
+// Condition operator can be defined in field tag option as `op` or `op=column`,
+// where op is one of eq, ne, gt, ge, lt, le, like, notLike, ilike, and optional column
+// is a name of target column (tag name is used by default), range bounds are also
+// supported (see WhereRange).
+// Postgres array columns can be filtered with any (value is an element of column) and
+// overlap (column has common elements with Array value) operators.
+//
+//	CreatedFrom time.Time     `db:"created_from,ge=created_at,omitempty"` // created_at >= ?
+//	MinAmount   int           `db:"amount,gt,omitempty"`                  // amount > ?
+//	Tag         string        `db:"tag,any=tags,omitempty"`               // ? = ANY(tags)
+//	AnyTags     Array[string] `db:"tags,overlap,omitempty"`               // tags && ?
+//
+// Tag options that are neither operators nor known column options (e.g. a typo `gte=created_at`)
+// make resulting condition fail to build, multiple operators in a tag cause panic.
+func (sm *Mapper) Where(conditions interface{}, options ...func(*Options)) squirrel.Sqlizer {
+	o := Options{}
+
+	for _, option := range options {
+		option(&o)
+	}
+
+	columns, values, fields := sm.columnsValuesFields(reflect.ValueOf(conditions), o, true)
+	if len(columns) == 0 {
+		return nil
+	}
+
+	return sm.and(columns, values, fields, o)
+}
+
+// WhereRange maps struct values as range conditions to squirrel.Sqlizer.
+//
+// Bounds of range are defined in field tag option `range` as `min` (column >= ?) or `max` (column <= ?),
+// so that multiple fields can refer to the same column. Fields with nil pointer values are skipped,
+// other fields are mapped as in Where.
+//
+//	MinPrice *int `db:"price,range=min"` // price >= ?
+//	MaxPrice *int `db:"price,range=max"` // price <= ?
+//
+// It returns nil if there are no conditions.
+func (sm *Mapper) WhereRange(conditions interface{}, options ...func(*Options)) squirrel.Sqlizer {
+	o := Options{}
+
+	for _, option := range options {
+		option(&o)
+	}
+
+	columns, values, fields := sm.columnsValuesFields(reflect.ValueOf(conditions), o, true)
+	n := 0
+
+	for i, val := range values {
+		if v := reflect.ValueOf(val); v.Kind() == reflect.Ptr && v.IsNil() {
+			continue
+		}
+
+		columns[n], values[n], fields[n] = columns[i], val, fields[i]
+		n++
+	}
+
+	if n == 0 {
+		return nil
+	}
+
+	return sm.and(columns[:n], values[:n], fields[:n], o)
+}
+
+// whereOperators maps field tag options to condition builders.
+var whereOperators = map[string]func(column string, val interface{}) squirrel.Sqlizer{
+	"eq":      func(column string, val interface{}) squirrel.Sqlizer { return squirrel.Eq{column: val} },
+	"ne":      func(column string, val interface{}) squirrel.Sqlizer { return squirrel.NotEq{column: val} },
+	"gt":      func(column string, val interface{}) squirrel.Sqlizer { return squirrel.Gt{column: val} },
+	"ge":      func(column string, val interface{}) squirrel.Sqlizer { return squirrel.GtOrEq{column: val} },
+	"lt":      func(column string, val interface{}) squirrel.Sqlizer { return squirrel.Lt{column: val} },
+	"le":      func(column string, val interface{}) squirrel.Sqlizer { return squirrel.LtOrEq{column: val} },
+	"like":    func(column string, val interface{}) squirrel.Sqlizer { return squirrel.Like{column: val} },
+	"notLike": func(column string, val interface{}) squirrel.Sqlizer { return squirrel.NotLike{column: val} },
+	"ilike":   func(column string, val interface{}) squirrel.Sqlizer { return squirrel.ILike{column: val} },
+	"any": func(column string, val interface{}) squirrel.Sqlizer {
+		return squirrel.Expr("? = ANY("+column+")", val)
+	},
+	"overlap": func(column string, val interface{}) squirrel.Sqlizer { return squirrel.Expr(column+" && ?", val) },
+}
+
+// operatorSQL maps field tag options to SQL operators for raw expression values.
+var operatorSQL = map[string]string{
+	"":        "=",
+	"eq":      "=",
+	"ne":      "<>",
+	"gt":      ">",
+	"ge":      ">=",
+	"lt":      "<",
+	"le":      "<=",
+	"like":    "LIKE",
+	"notLike": "NOT LIKE",
+	"ilike":   "ILIKE",
+	"overlap": "&&",
+}
+
+// exprCondition makes condition with raw expression if value is Quoted or squirrel.Sqlizer.
+func exprCondition(column, op string, val interface{}) (squirrel.Sqlizer, bool) {
+	switch v := val.(type) {
+	case Quoted:
+		if op == "any" {
+			return squirrel.Expr(string(v) + " = ANY(" + column + ")"), true
+		}
+
+		return squirrel.Expr(column + " " + operatorSQL[op] + " " + string(v)), true
+	case squirrel.Sqlizer:
+		if op == "any" {
+			return squirrel.Expr("? = ANY("+column+")", v), true
+		}
+
+		return squirrel.Expr(column+" "+operatorSQL[op]+" ?", v), true
+	}
+
+	return nil, false
+}
+
+// columnOptions are field tag options that are not condition operators.
+var columnOptions = map[string]bool{
+	"omitempty":      true,
+	ReadOnly:         true,
+	InsertOnly:       true,
+	Auto:             true,
+	Inline:           true,
+	Default:          true,
+	"serialIdentity": true, // SerialID of StorageOf.
+	"pk":             true, // PrimaryKey of StorageOf.
+}
+
+// fieldOperator returns condition operator and optional target column from field tag options.
+//
+// Error is returned for options that are neither operators nor column options.
+func fieldOperator(fi *reflectx.FieldInfo) (op string, column string, err error) {
+	for k, v := range fi.Options {
+		if k == "range" {
+			switch v {
+			case "min":
+				k, v = "ge", ""
+			case "max":
+				k, v = "le", ""
+			default:
+				panic(fmt.Sprintf("unknown range bound %q in tag of field %s, min or max expected", v, fi.Field.Name))
+			}
+		}
+
+		if _, ok := whereOperators[k]; !ok {
+			if columnOptions[k] {
+				continue
+			}
+
+			return "", "", fmt.Errorf("%w %q in tag of field %s", errUnknownTagOption, k, fi.Field.Name)
+		}
+
+		if op != "" {
+			panic(fmt.Sprintf("multiple operators in tag of field %s", fi.Field.Name))
+		}
+
+		op, column = k, v
+	}
+
+	return op, column, nil
+}
+
+// filterColumns returns columns that are compared by filter fields, operator target columns are resolved.
+func (sm *Mapper) filterColumns(filter interface{}) ([]string, error) {
+	tm, _ := sm.colType(reflect.ValueOf(filter))
+	res := make([]string, 0, len(tm.Index))
+
+	for _, fi := range tm.Index {
+		name := sm.fieldColName(fi)
+
+		if sm.skip(fi, name, nil) {
+			continue
+		}
+
+		_, target, err := fieldOperator(fi)
+		if err != nil {
+			return nil, err
+		}
+
+		if target != "" {
+			name = sm.colName(target)
+		}
+
+		res = append(res, name)
+	}
+
+	return res, nil
+}
+
+func (sm *Mapper) and(columns []string, values []interface{}, fields []*reflectx.FieldInfo, o Options) squirrel.And {
+	and := make(squirrel.And, 0, len(columns))
+
+	for i, column := range columns {
+		op, target, err := fieldOperator(fields[i])
+		if err != nil {
+			return append(and, errStmt{err: err})
+		}
+
+		if target != "" {
+			column = o.prepareColumn(sm.colName(target))
+		}
+
+		if expr, ok := exprCondition(column, op, values[i]); ok {
+			and = append(and, expr)
+		} else if op == "" || op == "eq" {
+			and = append(and, sm.eq(column, values[i], o))
+		} else {
+			and = append(and, whereOperators[op](column, values[i]))
+		}
+	}
+
+	return and
+}
+
+func (sm *Mapper) eq(column string, val interface{}, o Options) squirrel.Sqlizer {
+	if o.UseAnyArray && sm != nil && sm.Dialect == DialectPostgres {
+		v := reflect.ValueOf(val)
+		k := v.Kind()
+
+		if _, ok := val.(driver.Valuer); !ok && (k == reflect.Slice || k == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8 {
+			return squirrel.Expr(column+" = ANY(?)", pgArray{v: v})
+		}
+	}
+
+	return squirrel.Eq{column: val}
+}
+
+func (sm *Mapper) colType(v reflect.Value) (*reflectx.StructMap, bool) {
+	v = reflect.Indirect(v)
+	k := v.Kind()
+	t := v.Type()
+	skipValues := false
+
+	if k == reflect.Slice || k == reflect.Array {
+		t = t.Elem()
+
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		k = t.Kind()
+		skipValues = true
+	}
+
+	if k != reflect.Struct {
+		panic("struct or slice/array of struct expected in sql query mapper")
+	}
+
+	tm := sm.typeMap(t)
+
+	return tm, skipValues
+}
+
+func (sm *Mapper) skip(fi *reflectx.FieldInfo, name string, columns []string) bool {
+	if fi.Embedded {
+		return true
+	}
+
+	if fi.Field.Tag == "" && !sm.mapUntagged {
+		return true
+	}
+
+	if len(columns) > 0 && !hasColumn(columns, name) {
+		return true
+	}
+
+	return false
+}
+
+// skipStatement checks if field is excluded from statement by tag options.
+func skipStatement(fi *reflectx.FieldInfo, st statementType) bool {
+	switch st {
+	case statementInsert:
+		_, readOnly := fi.Options[ReadOnly]
+		_, auto := fi.Options[Auto]
+
+		return readOnly || auto
+	case statementUpdate:
+		_, readOnly := fi.Options[ReadOnly]
+		_, insertOnly := fi.Options[InsertOnly]
+
+		return readOnly || insertOnly
+	case statementOther:
+	}
+
+	return false
+}
+
+func hasColumn(columns []string, name string) bool {
+	for _, col := range columns {
+		if col == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isZero checks if field value is empty.
+//
+// Pointer is empty only if it is nil, a pointer to zero value is not empty.
+func isZero(colV reflect.Value, val interface{}) bool {
+	switch colV.Kind() { //nolint:exhaustive // Other kinds are compared with zero value.
+	case reflect.Slice, reflect.Map:
+		return colV.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return colV.IsNil()
+	default:
+		return val == nil || val == reflect.Zero(colV.Type()).Interface()
+	}
+}
+
+func hasType(types []reflect.Type, t reflect.Type) bool {
+	for _, tt := range types {
+		if tt == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ColumnsValues extracts columns and values from provided struct value.
+func (sm *Mapper) ColumnsValues(v reflect.Value, options ...func(*Options)) ([]string, []interface{}) {
+	o := Options{}
+
+	for _, option := range options {
+		option(&o)
+	}
+
+	return sm.columnsValues(v, o)
+}
+
+func (sm *Mapper) columnsValues(v reflect.Value, o Options) ([]string, []interface{}) {
+	columns, values, _ := sm.columnsValuesFields(v, o, false)
+
 	return columns, values
 }
 
+// columnsPool keeps column buffers of insert statements.
+//
+// Columns are copied by squirrel.InsertBuilder, so buffer can be reused after building.
+// Values are retained by squirrel.InsertBuilder as is and can not be pooled.
+var columnsPool = sync.Pool{
+	New: func() interface{} {
+		return new([]string)
+	},
+}
+
+// columnsValuesFields extracts columns, values and optionally field infos from provided struct value.
+func (sm *Mapper) columnsValuesFields(v reflect.Value, o Options, withFields bool) ([]string, []interface{}, []*reflectx.FieldInfo) {
+	return sm.appendColumnsValuesFields(nil, v, o, withFields)
+}
+
+// appendColumnsValuesFields extracts columns (appended to provided buffer), values and optionally
+// field infos from provided struct value.
+func (sm *Mapper) appendColumnsValuesFields(
+	columns []string, v reflect.Value, o Options, withFields bool,
+) ([]string, []interface{}, []*reflectx.FieldInfo) {
+	tm, skipValues := sm.colType(v)
+	values := make([]interface{}, 0, len(tm.Index))
+
+	if columns == nil {
+		columns = make([]string, 0, len(tm.Index))
+	}
+
+	var fields []*reflectx.FieldInfo
+
+	if withFields {
+		fields = make([]*reflectx.FieldInfo, 0, len(tm.Index))
+	}
+
+	for _, fi := range tm.Index {
+		name := sm.fieldColName(fi)
+
+		if sm.skip(fi, name, o.Columns) || skipStatement(fi, o.statement) {
+			continue
+		}
+
+		if expr, ok := o.ValueExprs[name]; ok && !skipValues {
+			values = append(values, squirrel.Expr(expr))
+		} else if !skipValues && hasColumn(o.UseDefault, name) {
+			values = append(values, sqlDefault)
+		} else if !skipValues {
+			colV := reflectx.FieldByIndexesReadOnly(v, fi.Index)
+			val := colV.Interface()
+
+			_, omitEmpty := fi.Options["omitempty"]
+
+			if o.IgnoreOmitEmpty && omitEmpty {
+				omitEmpty = false
+			}
+
+			if (o.SkipZeroValues || omitEmpty) && !hasType(o.NeverZero, colV.Type()) && sm.isZero(colV, val) {
+				def, hasDefault := fi.Options[Default]
+				if !hasDefault || o.statement != statementInsert {
+					continue
+				}
+
+				val = def
+			}
+
+			values = append(values, val)
+		}
+
+		if withFields {
+			fields = append(fields, fi)
+		}
+
+		columns = append(columns, o.prepareColumn(o.renameColumn(name)))
+	}
+
+	return columns, values, fields
+}
+
+func (o Options) renameColumn(name string) string {
+	if n, ok := o.RenameColumns[name]; ok {
+		return n
+	}
+
+	return name
+}
+
+func (o Options) prepareColumn(name string) string {
+	if o.PrepareColumn != nil {
+		return o.PrepareColumn(name)
+	}
+
+	return name
+}
+
+// ColumnInfo describes a column mapped from a structure field.
+type ColumnInfo struct {
+	// Name is a column name.
+	Name string
+
+	// FieldIndex is an index sequence of the field for reflect.Value.FieldByIndex.
+	FieldIndex []int
+
+	// GoType is a type of the field.
+	GoType reflect.Type
+
+	// Options are parsed field tag options, e.g. `omitempty` or `serialIdentity`.
+	Options map[string]string
+}
+
+// Columns returns columns information of a structure, pointer or slice of structures.
+//
+// Columns are mapped with same rules as in statements.
+func (sm *Mapper) Columns(v interface{}) []ColumnInfo {
+	tm, _ := sm.colType(reflect.ValueOf(v))
+	res := make([]ColumnInfo, 0, len(tm.Index))
+
+	for _, fi := range tm.Index {
+		name := sm.fieldColName(fi)
+
+		if sm.skip(fi, name, nil) {
+			continue
+		}
+
+		ci := ColumnInfo{
+			Name:       name,
+			FieldIndex: append([]int(nil), fi.Index...),
+			GoType:     fi.Field.Type,
+			Options:    make(map[string]string, len(fi.Options)),
+		}
+
+		for k, v := range fi.Options {
+			ci.Options[k] = v
+		}
+
+		res = append(res, ci)
+	}
+
+	return res
+}
+
+// Validate checks mapping of a structure, pointer or slice of structures.
+//
+// It returns error if multiple fields (e.g. from embedded structures) are mapped to the same column,
+// error contains column name and paths of conflicting fields.
+func (sm *Mapper) Validate(v interface{}) error {
+	tm, _ := sm.colType(reflect.ValueOf(v))
+	fields := make(map[string]*reflectx.FieldInfo, len(tm.Index))
+
+	for _, fi := range tm.Index {
+		name := sm.fieldColName(fi)
+
+		if sm.skip(fi, name, nil) {
+			continue
+		}
+
+		if prev, ok := fields[name]; ok {
+			return fmt.Errorf("%w %q: fields %s and %s", errDuplicateColumn, name, fieldPath(prev), fieldPath(fi))
+		}
+
+		fields[name] = fi
+	}
+
+	return nil
+}
+
+// fieldPath returns dot-separated path of Go field names.
+func fieldPath(fi *reflectx.FieldInfo) string {
+	path := fi.Field.Name
+
+	for p := fi.Parent; p != nil && p.Path != ""; p = p.Parent {
+		path = p.Field.Name + "." + path
+	}
+
+	return path
+}
+
 // FindColumnName returns column name of a database entity field.
 //
 // Entity field is defined by pointer to owner structure and pointer to field in that structure.
@@ -379,7 +1372,7 @@ func (sm *Mapper) FindColumnName(structPtr, fieldPtr interface{}) (string, error
 	for _, fi := range tm.Index {
 		fv := reflectx.FieldByIndexesReadOnly(v, fi.Index)
 		if fv.Addr().Interface() == fieldPtr {
-			return fi.Name, nil
+			return sm.fieldColName(fi), nil
 		}
 	}
 
@@ -403,13 +1396,18 @@ func (sm *Mapper) typeMap(t reflect.Type) *reflectx.StructMap {
 	index := make([]*reflectx.FieldInfo, 0, len(tm.Index))
 
 	for _, fi := range tm.Index {
+		// Inline structure is not a column itself, its fields are.
+		if _, ok := fi.Options[Inline]; ok && !fi.Embedded {
+			continue
+		}
+
 		skip := false
 		p := fi.Parent
 
 		// Field is allowed to be a column if does not have a named parent (with non-empty path)
-		// or all parents are embedded.
+		// or all parents are embedded or inline.
 		for p != nil && p.Path != "" {
-			if !p.Embedded {
+			if _, ok := p.Options[Inline]; !p.Embedded && !ok {
 				skip = true
 
 				break
@@ -427,7 +1425,7 @@ func (sm *Mapper) typeMap(t reflect.Type) *reflectx.StructMap {
 
 	tm.Index = index
 
-	if sm.types == nil {
+	if sm.types == nil || (sm.MaxCachedTypes > 0 && len(sm.types) >= sm.MaxCachedTypes) {
 		sm.types = make(map[reflect.Type]*reflectx.StructMap, 1)
 	}
 
@@ -436,12 +1434,58 @@ func (sm *Mapper) typeMap(t reflect.Type) *reflectx.StructMap {
 	return tm
 }
 
+// ClearCache removes cached field mapping of structure types.
+//
+// Please note, ReflectMapper has its own cache that is not affected.
+func (sm *Mapper) ClearCache() {
+	if sm == nil {
+		sm = defaultMapper
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.types = nil
+}
+
+// CachedTypes returns number of cached structure types.
+func (sm *Mapper) CachedTypes() int {
+	if sm == nil {
+		sm = defaultMapper
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	return len(sm.types)
+}
+
 // FindColumnNames returns column names mapped by a pointer to a field.
 func (sm *Mapper) FindColumnNames(structPtr interface{}) (map[interface{}]string, error) {
 	return sm.findColumnNames(structPtr, nil)
 }
 
 func (sm *Mapper) findColumnNames(structPtr interface{}, filter func(fi *reflectx.FieldInfo) (pass bool)) (map[interface{}]string, error) {
+	return sm.findNames(structPtr, filter, sm.fieldColName)
+}
+
+// selectedColumnNames returns names of columns in result of Select, columns of inline structure are
+// aliased with field path.
+func (sm *Mapper) selectedColumnNames(structPtr interface{}) (map[interface{}]string, error) {
+	return sm.findNames(structPtr, nil, func(fi *reflectx.FieldInfo) string {
+		if isInlined(fi) {
+			return fi.Path
+		}
+
+		return sm.fieldColName(fi)
+	})
+}
+
+func (sm *Mapper) findNames(
+	structPtr interface{},
+	filter func(fi *reflectx.FieldInfo) (pass bool),
+	name func(fi *reflectx.FieldInfo) string,
+) (map[interface{}]string, error) {
 	if structPtr == nil {
 		return nil, errNilArgument
 	}
@@ -466,7 +1510,7 @@ func (sm *Mapper) findColumnNames(structPtr interface{}, filter func(fi *reflect
 		}
 
 		fv := reflectx.FieldByIndexesReadOnly(v, fi.Index)
-		res[fv.Addr().Interface()] = fi.Name
+		res[fv.Addr().Interface()] = name(fi)
 	}
 
 	return res, nil
@@ -482,6 +1526,47 @@ func (sm *Mapper) Col(structPtr, fieldPtr interface{}) string {
 	return name
 }
 
+// quoteAlias quotes column alias according to dialect.
+func (sm *Mapper) quoteAlias(alias string) string {
+	if sm != nil && isMySQL(sm.Dialect) {
+		return QuoteBackticks(alias)
+	}
+
+	return QuoteANSI(alias)
+}
+
+// fieldColName returns column name of a field, names of inline parents are added as prefix.
+func (sm *Mapper) fieldColName(fi *reflectx.FieldInfo) string {
+	name := fi.Name
+
+	for p := fi.Parent; p != nil && p.Path != ""; p = p.Parent {
+		if !p.Embedded {
+			name = p.Name + "_" + name
+		}
+	}
+
+	return sm.colName(name)
+}
+
+// isInlined checks if field belongs to an inline structure.
+func isInlined(fi *reflectx.FieldInfo) bool {
+	for p := fi.Parent; p != nil && p.Path != ""; p = p.Parent {
+		if !p.Embedded {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (sm *Mapper) colName(name string) string {
+	if sm != nil && sm.ColumnNameMapper != nil {
+		return sm.ColumnNameMapper(name)
+	}
+
+	return name
+}
+
 func (sm *Mapper) reflectMapper() *reflectx.Mapper {
 	if sm != nil && sm.ReflectMapper != nil {
 		return sm.ReflectMapper
@@ -2,7 +2,12 @@ package sqluct_test
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/bool64/sqluct"
@@ -187,6 +192,32 @@ func TestInsertValueSlice_heterogeneous(t *testing.T) {
 	}, args)
 }
 
+func TestInsertValueSlice_heterogeneousOrder(t *testing.T) {
+	type Row struct {
+		A int    `db:"a,omitempty"`
+		B string `db:"b,omitempty"`
+		C string `db:"c,omitempty"`
+		D int    `db:"d,omitempty"`
+	}
+
+	sm := sqluct.Mapper{}
+	rows := []Row{{A: 1}, {C: "c", D: 4}, {B: "b"}}
+	prefix := func(o *sqluct.Options) {
+		o.PrepareColumn = func(col string) string { return "t." + col }
+	}
+
+	for i := 0; i < 20; i++ {
+		assertStatementArgs(t, "INSERT INTO t (t.a,t.b,t.c,t.d) VALUES (?,?,?,?),(?,?,?,?),(?,?,?,?)",
+			[]interface{}{1, "", "", 0, 0, "", "c", 4, 0, "b", "", 0},
+			sm.Insert(squirrel.Insert("t"), rows, prefix))
+	}
+
+	// Row with a subset of columns of first row.
+	assertStatementArgs(t, "INSERT INTO t (a,b) VALUES (?,?),(?,?)",
+		[]interface{}{1, "b", 2, ""},
+		sm.Insert(squirrel.Insert("t"), []Row{{A: 1, B: "b"}, {A: 2}}))
+}
+
 func TestInsertValueSlice_homogeneous(t *testing.T) {
 	z := []Sample{
 		{
@@ -351,6 +382,24 @@ func TestInsertValueSlicePtr(t *testing.T) {
 	}, args)
 }
 
+func TestInsertSliceOfPointers(t *testing.T) {
+	z := []*Sample{
+		{A: 1, DeeplyEmbedded: DeeplyEmbedded{SampleEmbedded: SampleEmbedded{B: 2.2, C: "3"}, E: "e!"}},
+		{A: 4, DeeplyEmbedded: DeeplyEmbedded{SampleEmbedded: SampleEmbedded{B: 5.5, C: "6"}, E: "ee!"}},
+	}
+
+	sm := sqluct.Mapper{}
+
+	assertStatementArgs(t, "INSERT INTO sample (a,meta,e,b,c) VALUES (?,?,?,?,?),(?,?,?,?,?)", []interface{}{
+		1, AnotherRow{}, "e!", 2.2, "3",
+		4, AnotherRow{}, "ee!", 5.5, "6",
+	}, sm.Insert(squirrel.Insert("sample"), z))
+	assertStatement(t, "SELECT a, meta, e, b, c FROM sample", sm.Select(squirrel.Select(), z).From("sample"))
+
+	_, _, err := sm.Insert(squirrel.Insert("sample"), []*Sample{z[0], nil}).ToSql()
+	require.EqualError(t, err, "nil item in slice at index 1")
+}
+
 func TestMapper_Update(t *testing.T) {
 	z := SampleEmbedded{
 		B: 2.2,
@@ -633,3 +682,848 @@ func assertStatement(t *testing.T, s string, qb sqluct.ToSQL) {
 	require.NoError(t, err)
 	assert.Equal(t, s, stmt)
 }
+
+func TestMapper_Columns(t *testing.T) {
+	sm := sqluct.Mapper{}
+
+	cols := sm.Columns([]Sample{})
+	require.Len(t, cols, 5)
+
+	assert.Equal(t, "a", cols[0].Name)
+	assert.Equal(t, []int{0}, cols[0].FieldIndex)
+	assert.Equal(t, reflect.TypeOf(0), cols[0].GoType)
+	assert.Equal(t, map[string]string{"omitempty": ""}, cols[0].Options)
+
+	assert.Equal(t, "meta", cols[1].Name)
+	assert.Equal(t, reflect.TypeOf(AnotherRow{}), cols[1].GoType)
+	assert.Empty(t, cols[1].Options)
+
+	assert.Equal(t, "b", cols[3].Name)
+	assert.Equal(t, []int{1, 0, 0}, cols[3].FieldIndex)
+	assert.Equal(t, reflect.TypeOf(0.0), cols[3].GoType)
+
+	// Returned values do not affect mapping.
+	cols[0].Options["foo"] = "bar"
+	cols[0].FieldIndex[0] = 10
+
+	assert.Equal(t, cols[1:], sm.Columns(&Sample{})[1:])
+	assert.Equal(t, map[string]string{"omitempty": ""}, sm.Columns(Sample{})[0].Options)
+	assert.Equal(t, []int{0}, sm.Columns(Sample{})[0].FieldIndex)
+}
+
+func TestMapper_excludedField(t *testing.T) {
+	type Row struct {
+		ID      int    `db:"id"`
+		Skipped string `db:"-"`
+		Ignored string `db:"-,omitempty"`
+		NoTag   string
+		Name    string `db:"name"`
+	}
+
+	r := Row{ID: 1, Skipped: "s", Ignored: "i", NoTag: "n", Name: "foo"}
+	sm := sqluct.Mapper{}
+
+	assertStatement(t, "INSERT INTO rows (id,name) VALUES (?,?)", sm.Insert(squirrel.Insert("rows"), r))
+	assertStatement(t, "INSERT INTO rows (id,name) VALUES (?,?),(?,?)", sm.Insert(squirrel.Insert("rows"), []Row{r, r}))
+	assertStatement(t, "UPDATE rows SET id = ?, name = ?", sm.Update(squirrel.Update("rows"), r))
+	assertStatement(t, "SELECT id, name FROM rows", sm.Select(squirrel.Select().From("rows"), r))
+	assert.Equal(t, squirrel.Eq{"id": 1, "name": "foo"}, sm.WhereEq(r))
+	assert.Len(t, sm.Columns(r), 2)
+
+	_, err := sm.FindColumnName(&r, &r.Skipped)
+	require.EqualError(t, err, "unknown field or row or not a pointer")
+}
+
+func TestMapper_ColumnNameMapper(t *testing.T) {
+	type Row struct {
+		ID   int    `db:"id"`
+		Name string `db:"name,omitempty"`
+	}
+
+	sm := &sqluct.Mapper{ColumnNameMapper: strings.ToUpper}
+	r := Row{ID: 1, Name: "foo"}
+
+	assertStatement(t, "INSERT INTO rows (ID,NAME) VALUES (?,?)", sm.Insert(squirrel.Insert("rows"), r))
+	assertStatement(t, "UPDATE rows SET ID = ?, NAME = ?", sm.Update(squirrel.Update("rows"), r, sqluct.Columns("ID", "NAME")))
+	assertStatement(t, "SELECT ID, NAME FROM rows", sm.Select(squirrel.Select().From("rows"), r))
+	assert.Equal(t, squirrel.Eq{"ID": 1, "NAME": "foo"}, sm.WhereEq(r))
+	assert.Equal(t, "NAME", sm.Col(&r, &r.Name))
+	assert.Equal(t, "ID", sm.Columns(r)[0].Name)
+
+	st := sqluct.Storage{Mapper: sm, IdentifierQuoter: sqluct.QuoteANSI}
+	assertStatement(t, `SELECT "ID", "NAME" FROM "rows"`, st.SelectStmt("rows", r))
+
+	rf := st.MakeReferencer()
+	rf.AddTableAlias(&r, "rows")
+	assert.Equal(t, `"rows"."NAME"`, rf.Ref(&r.Name))
+	assert.Equal(t, "NAME", rf.Col(&r.Name))
+	assertStatement(t, `UPDATE "rows" SET "NAME" = $1`, st.UpdateStmt("rows", r, sqluct.Columns(rf.Col(&r.Name))))
+}
+
+func TestMapper_WhereEqAny(t *testing.T) {
+	type Filter struct {
+		A int    `db:"a,omitempty"`
+		B string `db:"b,omitempty"`
+	}
+
+	sm := sqluct.Mapper{}
+	q := squirrel.Select("a").From("sample")
+
+	assertStatementArgs(t, "SELECT a FROM sample WHERE ((a = ? AND b IN (?)) OR (a = ?) OR (b IN (?,?)))", []interface{}{1, "x", 2, "y", "z"},
+		q.Where(sm.WhereEqAny([]struct {
+			A int      `db:"a,omitempty"`
+			B []string `db:"b,omitempty"`
+		}{
+			{A: 1, B: []string{"x"}},
+			{A: 2},
+			{B: []string{"y", "z"}},
+		})))
+
+	assertStatementArgs(t, "SELECT a FROM sample WHERE ((a = ? AND b = ?) OR (1=1))", []interface{}{1, "x"},
+		q.Where(sm.WhereEqAny(&[]Filter{{A: 1, B: "x"}, {}})))
+
+	assertStatementArgs(t, "SELECT a FROM sample WHERE ((a = ? AND b = ?) OR (a = ? AND b = ?))", []interface{}{1, "", 0, "y"},
+		q.Where(sm.WhereEqAny([]Filter{{A: 1}, {B: "y"}}, sqluct.IgnoreOmitEmpty)))
+
+	assertStatementArgs(t, "SELECT a FROM sample WHERE (1=0)", nil,
+		q.Where(sm.WhereEqAny([]Filter{})))
+
+	assert.Panics(t, func() {
+		sm.WhereEqAny(Filter{})
+	})
+}
+
+func assertStatementArgs(t *testing.T, s string, args []interface{}, qb sqluct.ToSQL) {
+	t.Helper()
+
+	stmt, a, err := qb.ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, s, stmt)
+	assert.Equal(t, args, a)
+}
+
+func TestZeroTimeIsValid(t *testing.T) {
+	type Row struct {
+		ID        int        `db:"id,omitempty"`
+		CreatedAt time.Time  `db:"created_at,omitempty"`
+		DeletedAt *time.Time `db:"deleted_at,omitempty"`
+		Amount    float64    `db:"amount"`
+	}
+
+	sm := sqluct.Mapper{}
+
+	assertStatementArgs(t, "INSERT INTO rows (amount) VALUES (?)", []interface{}{0.0},
+		sm.Insert(squirrel.Insert("rows"), Row{}))
+	assertStatementArgs(t, "INSERT INTO rows (created_at,amount) VALUES (?,?)", []interface{}{time.Time{}, 0.0},
+		sm.Insert(squirrel.Insert("rows"), Row{}, sqluct.ZeroTimeIsValid))
+	assert.Equal(t, squirrel.Eq(nil), sm.WhereEq(Row{}, sqluct.SkipZeroValues))
+	assert.Equal(t, squirrel.Eq{"created_at": time.Time{}}, sm.WhereEq(Row{}, sqluct.SkipZeroValues, sqluct.ZeroTimeIsValid))
+	assert.Equal(t, squirrel.Eq{"created_at": time.Time{}, "amount": 0.0},
+		sm.WhereEq(Row{}, sqluct.SkipZeroValues, sqluct.NeverZero(time.Time{}, 0.0)))
+}
+
+func TestMapper_Where(t *testing.T) {
+	type Filter struct {
+		Names []string `db:"name,omitempty"`
+		ID    int      `db:"id,omitempty"`
+	}
+
+	sm := &sqluct.Mapper{}
+	q := squirrel.Select("id").From("users")
+
+	assertStatementArgs(t, "SELECT id FROM users WHERE (name IN (?,?) AND id = ?)", []interface{}{"a", "b", 1},
+		q.Where(sm.Where(Filter{Names: []string{"a", "b"}, ID: 1}, sqluct.UseAnyArray)))
+	assertStatementArgs(t, "SELECT id FROM users", nil, q.Where(sm.Where(Filter{})))
+
+	sm.Dialect = sqluct.DialectPostgres
+	q = q.PlaceholderFormat(squirrel.Dollar)
+
+	assertStatementArgs(t, "SELECT id FROM users WHERE (name IN ($1,$2) AND id = $3)", []interface{}{"a", "b", 1},
+		q.Where(sm.Where(Filter{Names: []string{"a", "b"}, ID: 1})))
+
+	stmt, args, err := q.Where(sm.Where(Filter{Names: []string{"a", `b"\`}, ID: 1}, sqluct.UseAnyArray)).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM users WHERE (name = ANY($1) AND id = $2)", stmt)
+	require.Len(t, args, 2)
+	assert.Equal(t, 1, args[1])
+
+	v, err := args[0].(driver.Valuer).Value()
+	require.NoError(t, err)
+	assert.Equal(t, `{"a","b\"\\"}`, v)
+}
+
+func TestMapper_Where_operators(t *testing.T) {
+	type Filter struct {
+		CreatedFrom time.Time `db:"created_from,ge=created_at,omitempty"`
+		CreatedTo   time.Time `db:"created_to,lt=created_at,omitempty"`
+		MinAmount   int       `db:"amount,gt,omitempty"`
+		Name        string    `db:"name,like,omitempty"`
+		Status      string    `db:"status,ne,omitempty"`
+		ID          []int     `db:"id,eq,omitempty"`
+	}
+
+	sm := &sqluct.Mapper{}
+	q := squirrel.Select("id").From("orders")
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	assertStatementArgs(t, "SELECT id FROM orders WHERE (created_at >= ? AND created_at < ? AND amount > ? AND "+
+		"name LIKE ? AND status <> ? AND id IN (?,?))",
+		[]interface{}{ts, ts.Add(time.Hour), 10, "a%", "deleted", 1, 2},
+		q.Where(sm.Where(Filter{
+			CreatedFrom: ts, CreatedTo: ts.Add(time.Hour), MinAmount: 10, Name: "a%", Status: "deleted", ID: []int{1, 2},
+		})))
+
+	assertStatementArgs(t, "SELECT id FROM orders WHERE ((o.amount > ?) OR (o.created_at >= ?))",
+		[]interface{}{1, ts},
+		q.Where(sm.WhereEqAny([]Filter{{MinAmount: 1}, {CreatedFrom: ts}}, func(o *sqluct.Options) {
+			o.PrepareColumn = func(col string) string { return "o." + col }
+		})))
+
+	type UnknownOp struct {
+		A int `db:"a,gte=b"`
+	}
+
+	_, _, err := q.Where(sm.Where(UnknownOp{A: 1})).ToSql()
+	require.EqualError(t, err, `unknown tag option "gte" in tag of field A`)
+
+	type ColumnOpts struct {
+		A int `db:"a,omitempty,readonly,insertOnly,auto,default=1,pk,serialIdentity"`
+	}
+
+	assertStatementArgs(t, "SELECT id FROM orders WHERE (a = ?)", []interface{}{1},
+		q.Where(sm.Where(ColumnOpts{A: 1})))
+
+	type MultiOp struct {
+		A int `db:"a,gt,lt"`
+	}
+
+	assert.Panics(t, func() { sm.Where(MultiOp{A: 1}) })
+}
+
+func TestMapper_Where_expressions(t *testing.T) {
+	type Filter struct {
+		UpdatedAt  squirrel.Sqlizer `db:"updated_at,omitempty"`
+		ValidUntil squirrel.Sqlizer `db:"valid_until,gt,omitempty"`
+		OwnerID    sqluct.Quoted    `db:"owner_id,omitempty"`
+		ID         int              `db:"id,omitempty"`
+	}
+
+	sm := &sqluct.Mapper{}
+	q := squirrel.Select("id").From("docs d").PlaceholderFormat(squirrel.Dollar)
+
+	assertStatementArgs(t, "SELECT id FROM docs d WHERE (updated_at = now() AND valid_until > now() + $1 AND owner_id = d.author_id AND id = $2)",
+		[]interface{}{"1 day", 1},
+		q.Where(sm.Where(Filter{
+			UpdatedAt:  squirrel.Expr("now()"),
+			ValidUntil: squirrel.Expr("now() + ?", "1 day"),
+			OwnerID:    "d.author_id",
+			ID:         1,
+		})))
+
+	assertStatementArgs(t, "SELECT id FROM docs d WHERE ((owner_id = d.author_id) OR (id = $1))", []interface{}{2},
+		q.Where(sm.WhereEqAny([]Filter{{OwnerID: "d.author_id"}, {ID: 2}})))
+}
+
+func TestMapper_Where_anyArrayTypes(t *testing.T) {
+	sm := &sqluct.Mapper{Dialect: sqluct.DialectPostgres}
+	s := "s"
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, tc := range []struct {
+		val      interface{}
+		expected string
+	}{
+		{val: []int{1, -2, 3}, expected: "{1,-2,3}"},
+		{val: [2]uint16{4, 5}, expected: "{4,5}"},
+		{val: []float64{1.5, 2}, expected: "{1.5,2}"},
+		{val: []bool{true, false}, expected: "{true,false}"},
+		{val: []*string{&s, nil}, expected: `{"s",NULL}`},
+		{val: []interface{}{nil, 1, "a", []byte{0xab}}, expected: `{NULL,1,"a","\\xab"}`},
+		{val: []time.Time{ts}, expected: `{"2020-01-02T03:04:05Z"}`},
+		{val: []sql.NullInt64{{Int64: 1, Valid: true}, {}}, expected: "{1,NULL}"},
+		{val: []string{}, expected: "{}"},
+	} {
+		where := sm.Where(struct {
+			Val interface{} `db:"val"`
+		}{Val: tc.val}, sqluct.UseAnyArray)
+
+		_, args, err := where.ToSql()
+		require.NoError(t, err)
+		require.Len(t, args, 1)
+
+		v, err := args[0].(driver.Valuer).Value()
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, v)
+	}
+
+	_, args, err := sm.Where(struct {
+		Val []struct{} `db:"val"`
+	}{Val: []struct{}{{}}}, sqluct.UseAnyArray).ToSql()
+	require.NoError(t, err)
+
+	_, err = args[0].(driver.Valuer).Value()
+	require.EqualError(t, err, "array element 0: unsupported type struct {}")
+}
+
+func TestMapper_Where_anyArrayLarge(t *testing.T) {
+	ids := make([]int, 10000)
+	expected := make([]string, 0, len(ids))
+
+	for i := range ids {
+		ids[i] = i
+		expected = append(expected, strconv.Itoa(i))
+	}
+
+	filter := struct {
+		IDs []int `db:"id"`
+	}{IDs: ids}
+
+	sm := &sqluct.Mapper{Dialect: sqluct.DialectPostgres}
+
+	stmt, args, err := squirrel.Select("id").From("users").PlaceholderFormat(squirrel.Dollar).
+		Where(sm.Where(filter, sqluct.UseAnyArray)).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM users WHERE (id = ANY($1))", stmt)
+	require.Len(t, args, 1)
+
+	v, err := args[0].(driver.Valuer).Value()
+	require.NoError(t, err)
+	assert.Equal(t, "{"+strings.Join(expected, ",")+"}", v)
+
+	// Other dialects fall back to IN.
+	sm.Dialect = sqluct.DialectMySQL
+	stmt, args, err = squirrel.Select("id").From("users").Where(sm.Where(filter, sqluct.UseAnyArray)).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM users WHERE (id IN ("+squirrel.Placeholders(len(ids))+"))", stmt)
+	assert.Len(t, args, len(ids))
+}
+
+func BenchmarkMapper_Where_in(b *testing.B) {
+	benchmarkMapperWhere(b)
+}
+
+func BenchmarkMapper_Where_anyArray(b *testing.B) {
+	benchmarkMapperWhere(b, sqluct.UseAnyArray)
+}
+
+func benchmarkMapperWhere(b *testing.B, options ...func(*sqluct.Options)) {
+	b.Helper()
+
+	ids := make([]int, 10000)
+	for i := range ids {
+		ids[i] = i
+	}
+
+	filter := struct {
+		IDs []int `db:"id"`
+	}{IDs: ids}
+
+	sm := &sqluct.Mapper{Dialect: sqluct.DialectPostgres}
+	q := squirrel.Select("id").From("users").PlaceholderFormat(squirrel.Dollar)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, args, err := q.Where(sm.Where(filter, options...)).ToSql()
+		if err != nil {
+			b.Fail()
+		}
+
+		// Arguments are encoded by the driver.
+		for _, arg := range args {
+			if v, ok := arg.(driver.Valuer); ok {
+				if _, err := v.Value(); err != nil {
+					b.Fail()
+				}
+			}
+		}
+	}
+}
+
+func TestMapper_GroupBy(t *testing.T) {
+	type Dimensions struct {
+		UserID  int    `db:"user_id,omitempty"`
+		Country string `db:"country"`
+	}
+
+	sm := sqluct.Mapper{}
+	q := sm.Select(squirrel.Select(), Dimensions{}).Columns("SUM(amount) AS total").From("orders")
+
+	assert.Equal(t, q, sm.GroupBy(q, nil))
+	assertStatementArgs(t, "SELECT user_id, country, SUM(amount) AS total FROM orders GROUP BY user_id, country HAVING country = ?", []interface{}{"US"},
+		sm.GroupBy(q, Dimensions{}).Having(sm.WhereEq(Dimensions{Country: "US"})))
+	assertStatementArgs(t, "SELECT user_id, country, SUM(amount) AS total FROM orders GROUP BY country", nil,
+		sm.GroupBy(q, []Dimensions{}, sqluct.Columns("country")))
+}
+
+func TestMapper_OrderBy(t *testing.T) {
+	type Sorting struct {
+		DeletedAt *time.Time `db:"deleted_at"`
+		Name      string     `db:"name"`
+	}
+
+	sm := sqluct.Mapper{}
+	q := squirrel.Select("id").From("users")
+
+	assert.Equal(t, q, sm.OrderBy(q, nil))
+	assertStatement(t, "SELECT id FROM users ORDER BY deleted_at, name", sm.OrderBy(q, Sorting{}))
+	assertStatement(t, "SELECT id FROM users ORDER BY name DESC", sm.OrderBy(q, Sorting{}, sqluct.Columns("name"), sqluct.OrderDesc))
+	assertStatement(t, "SELECT id FROM users ORDER BY deleted_at IS NULL DESC, deleted_at, name",
+		sm.OrderBy(q, Sorting{}, sqluct.NullsFirst("deleted_at")))
+	assertStatement(t, "SELECT id FROM users ORDER BY deleted_at IS NULL, deleted_at DESC, name DESC",
+		sm.OrderBy(q, Sorting{}, sqluct.NullsLast("deleted_at"), sqluct.OrderDesc))
+
+	sm.Dialect = sqluct.DialectPostgres
+	rf := sqluct.Referencer{Mapper: &sm}
+	s := &Sorting{}
+	rf.AddTableAlias(s, "u")
+
+	assertStatement(t, "SELECT id FROM users ORDER BY u.deleted_at DESC NULLS LAST, u.name DESC",
+		sm.OrderBy(q, s, rf.ColumnsOf(s), sqluct.NullsLast(rf.Col(&s.DeletedAt)), sqluct.OrderDesc))
+	assertStatement(t, "SELECT id FROM users ORDER BY deleted_at NULLS FIRST, name",
+		sm.OrderBy(q, Sorting{}, sqluct.NullsFirst("deleted_at")))
+}
+
+func TestMapper_Validate(t *testing.T) {
+	type Meta struct {
+		ID        int       `db:"id"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+
+	type Row struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+		Meta
+	}
+
+	type ValidRow struct {
+		Name string `db:"name"`
+		Meta
+	}
+
+	sm := sqluct.Mapper{}
+
+	require.EqualError(t, sm.Validate(Row{}), `duplicate column "id": fields ID and Meta.ID`)
+	require.EqualError(t, sm.Validate([]Row{}), `duplicate column "id": fields ID and Meta.ID`)
+	require.NoError(t, sm.Validate(&ValidRow{}))
+}
+
+func TestMapper_inline(t *testing.T) {
+	type Address struct {
+		Street string `db:"street"`
+		City   string `db:"city,omitempty"`
+	}
+
+	type Row struct {
+		ID      int     `db:"id"`
+		Addr    Address `db:"addr,inline"`
+		Meta    Address `db:"meta"`
+		Billing Address `db:"billing,inline"`
+	}
+
+	sm := sqluct.Mapper{}
+	r := Row{ID: 1, Addr: Address{Street: "Main", City: "Berlin"}, Billing: Address{Street: "Side"}}
+
+	assertStatementArgs(t, "INSERT INTO rows (id,meta,addr_street,addr_city,billing_street) VALUES (?,?,?,?,?)",
+		[]interface{}{1, Address{}, "Main", "Berlin", "Side"},
+		sm.Insert(squirrel.Insert("rows"), r))
+	assertStatement(t, `SELECT id, meta, addr_street AS "addr.street", addr_city AS "addr.city", `+
+		`billing_street AS "billing.street", billing_city AS "billing.city" FROM rows`,
+		sm.Select(squirrel.Select(), Row{}).From("rows"))
+	assertStatementArgs(t, "SELECT id FROM rows WHERE addr_city = ?", []interface{}{"Berlin"},
+		squirrel.Select("id").From("rows").Where(sm.WhereEq(Row{Addr: Address{City: "Berlin"}}, sqluct.SkipZeroValues)))
+	assert.Equal(t, "billing_city", sm.Col(&r, &r.Billing.City))
+
+	sm.Dialect = sqluct.DialectMySQL
+
+	assertStatement(t, "SELECT addr_street AS `addr.street` FROM rows",
+		sm.Select(squirrel.Select(), Row{}, sqluct.Columns("addr_street")).From("rows"))
+}
+
+func TestMapper_ColumnsString(t *testing.T) {
+	type Row struct {
+		ID   int    `db:"id,omitempty"`
+		Name string `db:"name"`
+	}
+
+	sm := sqluct.Mapper{}
+	rf := sqluct.Referencer{Mapper: &sm, IdentifierQuoter: sqluct.QuoteANSI}
+	r := &Row{}
+	rf.AddTableAlias(r, "r")
+
+	assert.Equal(t, "id, name", sm.ColumnsString(Row{}))
+	assert.Equal(t, "name", sm.ColumnsString([]Row{}, sqluct.Columns("name")))
+	assert.Equal(t, `"r"."id", "r"."name"`, sm.ColumnsString(r, rf.ColumnsOf(r)))
+	assert.Equal(t, `"r"."id", "r"."name"`, rf.ColsString(r))
+}
+
+func TestMapper_readOnly(t *testing.T) {
+	type Row struct {
+		ID        int       `db:"id,readonly"`
+		CreatedAt time.Time `db:"created_at,insertOnly"`
+		Name      string    `db:"name"`
+		Version   int       `db:"version,readonly,omitempty"`
+	}
+
+	sm := sqluct.Mapper{}
+	ts := time.Now()
+	r := Row{ID: 1, CreatedAt: ts, Name: "foo", Version: 2}
+
+	assertStatementArgs(t, "INSERT INTO rows (created_at,name) VALUES (?,?)", []interface{}{ts, "foo"},
+		sm.Insert(squirrel.Insert("rows"), r))
+	assertStatementArgs(t, "INSERT INTO rows (created_at,name) VALUES (?,?),(?,?)", []interface{}{ts, "foo", ts, "foo"},
+		sm.Insert(squirrel.Insert("rows"), []Row{r, r}))
+	assertStatementArgs(t, "UPDATE rows SET name = ?", []interface{}{"foo"},
+		sm.Update(squirrel.Update("rows"), r))
+	assertStatementArgs(t, "SELECT id, created_at, name, version FROM rows", nil,
+		sm.Select(squirrel.Select().From("rows"), r))
+	assert.Equal(t, squirrel.Eq{"id": 1, "created_at": ts, "name": "foo", "version": 2}, sm.WhereEq(r))
+}
+
+func TestMapper_auto(t *testing.T) {
+	type Row struct {
+		ID        int       `db:"id,auto"`
+		CreatedAt time.Time `db:"created_at,auto"`
+		Name      string    `db:"name"`
+	}
+
+	sm := sqluct.Mapper{}
+	ts := time.Now()
+	r := Row{ID: 1, CreatedAt: ts, Name: "foo"}
+
+	assertStatementArgs(t, "INSERT INTO rows (name) VALUES (?)", []interface{}{"foo"},
+		sm.Insert(squirrel.Insert("rows"), r))
+	assertStatementArgs(t, "INSERT INTO rows (name) VALUES (?),(?)", []interface{}{"foo", "bar"},
+		sm.Insert(squirrel.Insert("rows"), []Row{r, {Name: "bar"}}))
+	assertStatementArgs(t, "INSERT INTO rows (name) VALUES (?)", []interface{}{"foo"},
+		sm.Insert(squirrel.Insert("rows"), r, sqluct.IgnoreOmitEmpty))
+	assertStatementArgs(t, "UPDATE rows SET id = ?, created_at = ?, name = ?", []interface{}{1, ts, "foo"},
+		sm.Update(squirrel.Update("rows"), r))
+	assertStatementArgs(t, "SELECT id, created_at, name FROM rows", nil,
+		sm.Select(squirrel.Select().From("rows"), r))
+}
+
+func TestMapper_ValuesLiteral(t *testing.T) {
+	type Item struct {
+		ID    int    `db:"id"`
+		Name  string `db:"name,omitempty"`
+		Price int    `db:"price"`
+	}
+
+	items := []Item{{ID: 1, Name: "a"}, {ID: 2}}
+
+	for _, tc := range []struct {
+		dialect sqluct.Dialect
+		lit     string
+	}{
+		{dialect: sqluct.DialectPostgres, lit: "(VALUES (?,?),(?,?)) AS t(id, name)"},
+		{dialect: sqluct.DialectMySQL, lit: "(VALUES ROW(?,?),ROW(?,?)) AS t(id, name)"},
+		{dialect: sqluct.DialectSQLite3, lit: "(SELECT ? AS id, ? AS name UNION ALL SELECT ?, ?) AS t"},
+		{dialect: sqluct.DialectMariaDB, lit: "(SELECT ? AS id, ? AS name UNION ALL SELECT ?, ?) AS t"},
+	} {
+		sm := sqluct.Mapper{Dialect: tc.dialect}
+		lit, args := sm.ValuesLiteral(items, "t", "id", "name")
+		assert.Equal(t, tc.lit, lit, tc.dialect)
+		assert.Equal(t, []interface{}{1, "a", 2, ""}, args, tc.dialect)
+	}
+
+	sm := sqluct.Mapper{}
+
+	lit, args := sm.ValuesLiteral(Item{ID: 3, Price: 10}, "t")
+	assert.Equal(t, "(VALUES (?,?,?)) AS t(id, name, price)", lit)
+	assert.Equal(t, []interface{}{3, "", 10}, args)
+
+	lit, args = sm.ValuesLiteral([]Item{}, "t")
+	assert.Empty(t, lit)
+	assert.Empty(t, args)
+
+	lit, args = sm.ValuesLiteral([]*Item{nil, {ID: 1}, nil, {ID: 2}}, "t", "id")
+	assert.Equal(t, "(VALUES (?),(?)) AS t(id)", lit)
+	assert.Equal(t, []interface{}{1, 2}, args)
+
+	lit, args = sm.ValuesLiteral([]*Item{nil}, "t")
+	assert.Empty(t, lit)
+	assert.Empty(t, args)
+
+	lit, args = sm.ValuesLiteral(Item{ID: 1, Price: 5}, "t", "id", "price")
+	assertStatementArgs(t, "SELECT items.id FROM items JOIN (VALUES ($1,$2)) AS t(id, price) ON t.id = items.id",
+		[]interface{}{1, 5},
+		squirrel.Select("items.id").From("items").PlaceholderFormat(squirrel.Dollar).
+			JoinClause("JOIN "+lit+" ON t.id = items.id", args...))
+}
+
+func TestMapper_ClearCache(t *testing.T) {
+	sm := &sqluct.Mapper{MaxCachedTypes: 2}
+
+	assert.Equal(t, 0, sm.CachedTypes())
+
+	sm.ColumnsString(struct {
+		A int `db:"a"`
+	}{})
+	sm.ColumnsString(struct {
+		B int `db:"b"`
+	}{})
+	assert.Equal(t, 2, sm.CachedTypes())
+
+	assert.Equal(t, "c", sm.ColumnsString(struct {
+		C int `db:"c"`
+	}{}))
+	assert.Equal(t, 1, sm.CachedTypes())
+
+	sm.ClearCache()
+	assert.Equal(t, 0, sm.CachedTypes())
+
+	var nilMapper *sqluct.Mapper
+
+	nilMapper.ClearCache()
+	assert.Equal(t, 0, nilMapper.CachedTypes())
+}
+
+func TestMapper_Select_rowLock(t *testing.T) {
+	type Job struct {
+		ID int `db:"id"`
+	}
+
+	sm := &sqluct.Mapper{Dialect: sqluct.DialectPostgres}
+	q := squirrel.Select().From("jobs")
+
+	assertStatement(t, "SELECT id FROM jobs FOR UPDATE", sm.Select(q, Job{}, sqluct.ForUpdate))
+	assertStatement(t, "SELECT id FROM jobs FOR SHARE NOWAIT", sm.Select(q, Job{}, sqluct.ForShare, sqluct.NoWait))
+	assertStatement(t, "SELECT id FROM jobs FOR UPDATE SKIP LOCKED", sm.Select(q, Job{}, sqluct.SkipLocked))
+
+	sm.Dialect = sqluct.DialectMySQL
+	assertStatement(t, "SELECT id FROM jobs LIMIT 10 FOR UPDATE SKIP LOCKED",
+		sm.Select(q, Job{}, sqluct.ForUpdate, sqluct.SkipLocked).Limit(10))
+
+	sm.Dialect = sqluct.DialectSQLite3
+	assert.PanicsWithValue(t, `can not apply row locking for dialect "sqlite3"`, func() {
+		sm.Select(q, Job{}, sqluct.ForUpdate)
+	})
+
+	sm.Dialect = sqluct.DialectUnknown
+	assert.PanicsWithValue(t, "can not apply row locking for unknown dialect", func() {
+		sm.Select(q, Job{}, sqluct.ForUpdate)
+	})
+}
+
+func TestMapper_Insert_defaultTag(t *testing.T) {
+	type Task struct {
+		ID       int    `db:"id,omitempty"`
+		Status   string `db:"status,omitempty,default=new"`
+		Priority int    `db:"priority,omitempty,default=5"`
+	}
+
+	sm := &sqluct.Mapper{}
+	q := squirrel.Insert("tasks")
+
+	assertStatementArgs(t, "INSERT INTO tasks (status,priority) VALUES (?,?)", []interface{}{"new", "5"},
+		sm.Insert(q, Task{}))
+	assertStatementArgs(t, "INSERT INTO tasks (id,status,priority) VALUES (?,?,?)", []interface{}{1, "done", 1},
+		sm.Insert(q, Task{ID: 1, Status: "done", Priority: 1}))
+	assertStatementArgs(t, "INSERT INTO tasks (id,status,priority) VALUES (?,?,?),(?,?,?)",
+		[]interface{}{1, "new", "5", 2, "done", "5"},
+		sm.Insert(q, []Task{{ID: 1}, {ID: 2, Status: "done"}}))
+
+	// Default is not used in UPDATE and conditions.
+	assertStatementArgs(t, "UPDATE tasks SET id = ?", []interface{}{1}, sm.Update(squirrel.Update("tasks"), Task{ID: 1}))
+	assertStatementArgs(t, "SELECT id FROM tasks WHERE (id = ?)", []interface{}{1},
+		squirrel.Select("id").From("tasks").Where(sm.Where(Task{ID: 1})))
+}
+
+func TestMapper_WhereEqOrdered(t *testing.T) {
+	type Filter struct {
+		TenantID int    `db:"tenant_id"`
+		Status   string `db:"status,omitempty"`
+		Age      []int  `db:"age,omitempty"`
+	}
+
+	sm := &sqluct.Mapper{}
+	q := squirrel.Select("id").From("users")
+
+	assertStatementArgs(t, "SELECT id FROM users WHERE (tenant_id = ? AND status = ? AND age IN (?,?))",
+		[]interface{}{1, "active", 20, 30},
+		q.Where(sm.WhereEqOrdered(Filter{TenantID: 1, Status: "active", Age: []int{20, 30}})))
+
+	assertStatement(t, "SELECT id FROM users", q.Where(sm.WhereEqOrdered(struct {
+		ID int `db:"id,omitempty"`
+	}{})))
+}
+
+func TestMapper_WhereRange(t *testing.T) {
+	type Filter struct {
+		Category string `db:"category,omitempty"`
+		MinPrice *int   `db:"price,range=min"`
+		MaxPrice *int   `db:"price,range=max"`
+	}
+
+	sm := &sqluct.Mapper{}
+	q := squirrel.Select("id").From("products")
+	minPrice, maxPrice := 10, 20
+
+	assertStatementArgs(t, "SELECT id FROM products WHERE (category = ? AND price >= ? AND price <= ?)",
+		[]interface{}{"books", &minPrice, &maxPrice},
+		q.Where(sm.WhereRange(Filter{Category: "books", MinPrice: &minPrice, MaxPrice: &maxPrice})))
+
+	assertStatementArgs(t, "SELECT id FROM products WHERE (price <= ?)",
+		[]interface{}{&maxPrice},
+		q.Where(sm.WhereRange(Filter{MaxPrice: &maxPrice})))
+
+	assertStatement(t, "SELECT id FROM products", q.Where(sm.WhereRange(Filter{})))
+
+	assert.Panics(t, func() {
+		sm.WhereRange(struct {
+			Price int `db:"price,range=low"`
+		}{})
+	})
+}
+
+func TestNewMapperFunc(t *testing.T) {
+	type User struct {
+		Age       int
+		FirstName string
+		CreatedAt time.Time
+		Secret    string `db:"-"`
+		Email     string `db:"email_address,omitempty"`
+	}
+
+	toSnakeCase := func(s string) string {
+		var b strings.Builder
+
+		for i, r := range s {
+			if r >= 'A' && r <= 'Z' {
+				if i > 0 {
+					b.WriteByte('_')
+				}
+
+				r += 'a' - 'A'
+			}
+
+			b.WriteRune(r)
+		}
+
+		return b.String()
+	}
+
+	sm := sqluct.NewMapperFunc("db", toSnakeCase)
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assertStatementArgs(t, "INSERT INTO users (age,first_name,created_at) VALUES (?,?,?)", []interface{}{1, "John", ts},
+		sm.Insert(squirrel.Insert("users"), User{Age: 1, FirstName: "John", CreatedAt: ts, Secret: "s"}))
+
+	assertStatementArgs(t, "SELECT age, first_name, created_at, email_address FROM users", nil,
+		sm.Select(squirrel.Select().From("users"), User{}))
+
+	rf := sqluct.Referencer{Mapper: sm}
+	u := &User{}
+	rf.AddTableAlias(u, "u")
+
+	assert.Equal(t, "u.first_name", rf.Ref(&u.FirstName))
+	assert.Equal(t, "u.email_address", rf.Ref(&u.Email))
+
+	// Fields without tags are not mapped by default.
+	assertStatementArgs(t, "SELECT email_address FROM users", nil,
+		(&sqluct.Mapper{}).Select(squirrel.Select().From("users"), User{}))
+}
+
+func TestMapper_Insert_pointers(t *testing.T) {
+	type Row struct {
+		Plain *int `db:"plain"`
+		Omit  *int `db:"omit,omitempty"`
+	}
+
+	zero := 0
+	one := 1
+	sm := sqluct.Mapper{}
+
+	for _, tc := range []struct {
+		name   string
+		row    Row
+		skip   bool
+		query  string
+		values []interface{}
+	}{
+		{
+			name:   "nil",
+			query:  "INSERT INTO rows (plain) VALUES (?)",
+			values: []interface{}{(*int)(nil)},
+		},
+		{
+			name:  "nil, skip zero values",
+			skip:  true,
+			query: "INSERT INTO rows VALUES ()",
+		},
+		{
+			name:   "zero",
+			row:    Row{Plain: &zero, Omit: &zero},
+			query:  "INSERT INTO rows (plain,omit) VALUES (?,?)",
+			values: []interface{}{&zero, &zero},
+		},
+		{
+			name:   "zero, skip zero values",
+			row:    Row{Plain: &zero, Omit: &zero},
+			skip:   true,
+			query:  "INSERT INTO rows (plain,omit) VALUES (?,?)",
+			values: []interface{}{&zero, &zero},
+		},
+		{
+			name:   "non-zero",
+			row:    Row{Plain: &one, Omit: &one},
+			query:  "INSERT INTO rows (plain,omit) VALUES (?,?)",
+			values: []interface{}{&one, &one},
+		},
+		{
+			name:   "mixed, skip zero values",
+			row:    Row{Omit: &one},
+			skip:   true,
+			query:  "INSERT INTO rows (omit) VALUES (?)",
+			values: []interface{}{&one},
+		},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			var options []func(*sqluct.Options)
+
+			if tc.skip {
+				options = append(options, sqluct.SkipZeroValues)
+			}
+
+			assertStatementArgs(t, tc.query, tc.values, sm.Insert(squirrel.Insert("rows"), tc.row, options...))
+		})
+	}
+
+	// NULL is inserted for nil pointer.
+	v, err := driver.DefaultParameterConverter.ConvertValue((*int)(nil))
+	require.NoError(t, err)
+	assert.Nil(t, v)
+}
+
+type nilMapperZero struct {
+	V int
+}
+
+func TestMapper_RegisterZeroFunc_nil(t *testing.T) {
+	var sm *sqluct.Mapper
+
+	// Registration on nil Mapper applies to default mapper used by nil Mapper and Storage.
+	sm.RegisterZeroFunc(nilMapperZero{}, func(v reflect.Value) bool {
+		return v.Interface().(nilMapperZero).V < 0 //nolint:errcheck,forcetypeassert
+	})
+
+	type row struct {
+		ID  int           `db:"id"`
+		Val nilMapperZero `db:"val,omitempty"`
+	}
+
+	query, args, err := sm.Update(squirrel.Update("t"), row{ID: 1, Val: nilMapperZero{V: -1}}).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "UPDATE t SET id = ?", query)
+	assert.Equal(t, []interface{}{1}, args)
+
+	st := sqluct.NewStorage(nil)
+	query, args, err = st.UpdateStmt("t", row{ID: 1, Val: nilMapperZero{V: -1}}).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "UPDATE t SET id = $1", query)
+	assert.Equal(t, []interface{}{1}, args)
+}
@@ -0,0 +1,181 @@
+package sqluct
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Redacted replaces redacted statement arguments.
+const Redacted = "<redacted>"
+
+// RedactArgsByIndex makes Storage.RedactArgs function to redact arguments at indexes (starting from 0).
+func RedactArgsByIndex(indexes ...int) func(stmt string, args []interface{}) []interface{} {
+	return func(_ string, args []interface{}) []interface{} {
+		for _, i := range indexes {
+			if i >= 0 && i < len(args) {
+				args[i] = Redacted
+			}
+		}
+
+		return args
+	}
+}
+
+// RedactArgsByColumn makes Storage.RedactArgs function to redact arguments bound to columns.
+//
+// Columns are detected in statement heuristically, in comparisons (e.g. `col = ?`, `"t"."col" IN ($1,$2)`)
+// and in column lists of INSERT (e.g. `INSERT INTO t (a,col) VALUES (?,?)`).
+// Column names are matched case-insensitively without quotes and table prefix.
+func RedactArgsByColumn(columns ...string) func(stmt string, args []interface{}) []interface{} {
+	cols := make(map[string]bool, len(columns))
+
+	for _, c := range columns {
+		cols[strings.ToLower(c)] = true
+	}
+
+	return func(stmt string, args []interface{}) []interface{} {
+		for _, i := range columnArgs(stmt, cols) {
+			if i >= 0 && i < len(args) {
+				args[i] = Redacted
+			}
+		}
+
+		return args
+	}
+}
+
+var (
+	comparisonRe  = regexp.MustCompile("(?i)([\\w.\"`]+)\\s*(=|<>|!=|<=|>=|<|>|\\s(?:not\\s+)?i?like\\s|\\s(?:not\\s+)?in\\s*\\()\\s*")
+	insertValueRe = regexp.MustCompile(`(?is)\binsert\b.*?\(([^)]*)\)\s*values\s*`)
+)
+
+// columnArgs returns argument indexes bound to columns.
+func columnArgs(stmt string, cols map[string]bool) []int {
+	placeholders := findPlaceholders(stmt)
+
+	var res []int
+
+	for _, m := range comparisonRe.FindAllStringSubmatchIndex(stmt, -1) {
+		if !cols[columnName(stmt[m[2]:m[3]])] {
+			continue
+		}
+
+		end := m[1]
+
+		// Placeholders of IN list up to closing parenthesis.
+		if strings.HasSuffix(stmt[m[4]:m[5]], "(") {
+			if p := strings.IndexByte(stmt[end:], ')'); p >= 0 {
+				for pos := end; pos < end+p; pos++ {
+					if i, ok := placeholders[pos]; ok {
+						res = append(res, i)
+					}
+				}
+			}
+
+			continue
+		}
+
+		if i, ok := placeholders[end]; ok {
+			res = append(res, i)
+		}
+	}
+
+	if m := insertValueRe.FindStringSubmatchIndex(stmt); m != nil {
+		var colIdx []bool
+
+		for _, c := range strings.Split(stmt[m[2]:m[3]], ",") {
+			colIdx = append(colIdx, cols[columnName(c)])
+		}
+
+		res = append(res, valuesArgs(stmt, m[1], colIdx, placeholders)...)
+	}
+
+	return res
+}
+
+// valuesArgs returns argument indexes of redacted columns in VALUES tuples starting at pos.
+func valuesArgs(stmt string, pos int, redact []bool, placeholders map[int]int) []int {
+	var (
+		res   []int
+		depth int
+		item  int
+	)
+
+	for ; pos < len(stmt); pos++ {
+		switch stmt[pos] {
+		case '(':
+			if depth == 0 {
+				item = 0
+			}
+
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 1 {
+				item++
+			}
+		case ' ', '\t', '\n', '\r':
+		default:
+			if depth == 0 {
+				return res
+			}
+
+			if i, ok := placeholders[pos]; ok && item < len(redact) && redact[item] {
+				res = append(res, i)
+			}
+		}
+	}
+
+	return res
+}
+
+// findPlaceholders maps positions of `?` and `$N` placeholders to argument indexes.
+//
+// Quoted strings and comments are skipped.
+func findPlaceholders(stmt string) map[int]int {
+	res := make(map[int]int)
+	cnt := 0
+
+	for pos := 0; pos < len(stmt); pos++ {
+		switch stmt[pos] {
+		case '\'':
+			pos = skipQuoted(stmt, pos, '\'')
+		case '-':
+			if strings.HasPrefix(stmt[pos:], "--") {
+				pos = skipUntil(stmt, pos, "\n") - 1
+			}
+		case '/':
+			if strings.HasPrefix(stmt[pos:], "/*") {
+				pos = skipUntil(stmt, pos+2, "*/") - 1
+			}
+		case '?':
+			res[pos] = cnt
+			cnt++
+		case '$':
+			end := pos + 1
+
+			for end < len(stmt) && stmt[end] >= '0' && stmt[end] <= '9' {
+				end++
+			}
+
+			if n, err := strconv.Atoi(stmt[pos+1 : end]); err == nil {
+				res[pos] = n - 1
+			}
+		}
+	}
+
+	return res
+}
+
+// columnName returns lower-cased unquoted column name without table prefix.
+func columnName(ref string) string {
+	ref = strings.TrimSpace(ref)
+
+	if p := strings.LastIndexByte(ref, '.'); p >= 0 {
+		ref = ref[p+1:]
+	}
+
+	return strings.ToLower(strings.Trim(ref, "\"`"))
+}
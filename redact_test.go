@@ -0,0 +1,92 @@
+package sqluct_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bool64/sqluct"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactArgsByColumn(t *testing.T) {
+	r := sqluct.RedactArgsByColumn("email", "Password")
+	x := sqluct.Redacted
+
+	for _, tc := range []struct {
+		stmt     string
+		args     []interface{}
+		expected []interface{}
+	}{
+		{
+			stmt:     "SELECT id FROM users WHERE email = ? AND id > ?",
+			args:     []interface{}{"a@b.c", 1},
+			expected: []interface{}{x, 1},
+		},
+		{
+			stmt:     `SELECT id FROM users WHERE "users"."id" = $2 AND "users"."email" LIKE $1`,
+			args:     []interface{}{"a%", 1},
+			expected: []interface{}{x, 1},
+		},
+		{
+			stmt:     "SELECT id FROM users WHERE name = '?' AND email IN (?,?) AND id = ?",
+			args:     []interface{}{"a", "b", 1},
+			expected: []interface{}{x, x, 1},
+		},
+		{
+			stmt:     "UPDATE users SET password = $1, name = $2 WHERE id = $3",
+			args:     []interface{}{"secret", "John", 1},
+			expected: []interface{}{x, "John", 1},
+		},
+		{
+			stmt:     "INSERT INTO users (id,`email`,name) VALUES (?,?,?),(?,?,?) ON CONFLICT DO NOTHING",
+			args:     []interface{}{1, "a@b.c", "John", 2, "d@e.f", "Jane"},
+			expected: []interface{}{1, x, "John", 2, x, "Jane"},
+		},
+		{
+			stmt:     "/* why? */ SELECT id FROM users -- any?\nWHERE email = ? AND id = ?",
+			args:     []interface{}{"a@b.c", 1},
+			expected: []interface{}{x, 1},
+		},
+		{
+			stmt:     "INSERT INTO users (id,email) VALUES ($1,lower($2))",
+			args:     []interface{}{1, "A@b.c"},
+			expected: []interface{}{1, x},
+		},
+	} {
+		t.Run(tc.stmt, func(t *testing.T) {
+			assert.Equal(t, tc.expected, r(tc.stmt, tc.args))
+		})
+	}
+}
+
+func TestRedactArgsByIndex(t *testing.T) {
+	assert.Equal(t, []interface{}{1, sqluct.Redacted, 3},
+		sqluct.RedactArgsByIndex(1, 5, -1)("", []interface{}{1, 2, 3}))
+}
+
+func TestStorage_RedactArgs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	st.RedactArgs = sqluct.RedactArgsByColumn("email")
+
+	var traced []interface{}
+
+	st.Trace = func(ctx context.Context, stmt string, args []interface{}) (context.Context, func(error)) {
+		traced = args
+
+		return ctx, func(error) {}
+	}
+
+	mock.ExpectExec(`UPDATE users SET email = \$1 WHERE id = \$2`).WithArgs("a@b.c", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err = st.Exec(context.Background(), sqluct.Stmt("UPDATE users SET email = $1 WHERE id = $2", "a@b.c", 1))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{sqluct.Redacted, 1}, traced)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
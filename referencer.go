@@ -2,10 +2,13 @@ package sqluct
 
 import (
 	"fmt"
+	"reflect"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx/reflectx"
 )
 
 // QuoteANSI adds double quotes to symbols names.
@@ -54,6 +57,8 @@ func QuoteNoop(tableAndColumn ...string) string {
 }
 
 // Referencer maintains a list of string references to fields and table aliases.
+//
+// Referencer is safe for concurrent use, so it can be set up once and shared across goroutines.
 type Referencer struct {
 	Mapper *Mapper
 
@@ -61,10 +66,21 @@ type Referencer struct {
 	// Default QuoteNoop.
 	IdentifierQuoter func(tableAndColumn ...string) string
 
-	refs        map[interface{}]Quoted
-	quotedCols  map[interface{}]Quoted
-	columnNames map[interface{}]string
-	structRefs  map[interface{}][]string
+	// RawIdentifiers disables validation of table names and aliases when IdentifierQuoter is not set.
+	//
+	// Without quoter, table names and aliases are rendered as is, so by default they are required to be
+	// plain identifiers (e.g. `users` or `public.users`) to prevent SQL injection, Referencer panics otherwise.
+	// Enable RawIdentifiers to use expressions intentionally.
+	RawIdentifiers bool
+
+	mu           sync.RWMutex
+	refs         map[interface{}]Quoted
+	quotedCols   map[interface{}]Quoted
+	columnNames  map[interface{}]string
+	structRefs   map[interface{}][]string
+	structNames  map[interface{}][]string
+	structFields map[interface{}]map[interface{}]string
+	aliases      map[interface{}]string
 }
 
 // ColumnsOf makes a Mapper option to prefix columns with table alias.
@@ -79,7 +95,10 @@ func (r *Referencer) ColumnsOf(rowStructPtr interface{}) func(o *Options) {
 	case Quoted:
 		table = v
 	default:
+		r.mu.RLock()
 		t, found := r.refs[rowStructPtr]
+		r.mu.RUnlock()
+
 		if !found {
 			panic("row structure pointer needs to be added first with AddTableAlias")
 		}
@@ -94,6 +113,128 @@ func (r *Referencer) ColumnsOf(rowStructPtr interface{}) func(o *Options) {
 	}
 }
 
+// UseDefault makes a Mapper option to insert DEFAULT keyword instead of values of fields.
+//
+// Field pointers need to be added first with AddTableAlias.
+func (r *Referencer) UseDefault(fieldPtrs ...interface{}) func(o *Options) {
+	cols := make([]string, 0, len(fieldPtrs))
+
+	for _, ptr := range fieldPtrs {
+		cols = append(cols, r.Col(ptr))
+	}
+
+	return func(o *Options) {
+		o.UseDefault = append(o.UseDefault, cols...)
+	}
+}
+
+// ValueExpr makes a Mapper option to insert SQL expression instead of value of field.
+//
+//	sm.Insert(q, row, rf.ValueExpr(&row.CreatedAt, "now()"))
+//
+// Field pointer needs to be added first with AddTableAlias.
+func (r *Referencer) ValueExpr(fieldPtr interface{}, expr string) func(o *Options) {
+	col := r.Col(fieldPtr)
+
+	return func(o *Options) {
+		if o.ValueExprs == nil {
+			o.ValueExprs = make(map[string]string)
+		}
+
+		o.ValueExprs[col] = expr
+	}
+}
+
+// OnConflict makes a Mapper option to add ON CONFLICT clause to INSERT.
+//
+// Conflict target is made of columns of conflict field pointers with optional index predicate
+// to match partial unique index. Predicate can be built with Expr and NoTable references,
+// so that columns are resolved and quoted by Referencer. Columns of update field pointers are updated
+// with excluded values (this requires conflict target), DO NOTHING is used if there are no update fields.
+//
+//	sm.Insert(q, user, rf.OnConflict(
+//		[]interface{}{&u.Email}, rf.Expr("%s IS NULL", sqluct.NoTable(&u.DeletedAt)),
+//		&u.Name,
+//	))
+//	// ON CONFLICT (email) WHERE deleted_at IS NULL DO UPDATE SET name = EXCLUDED.name
+//
+// Field pointers need to be added first with AddTableAlias.
+func (r *Referencer) OnConflict(conflictPtrs []interface{}, predicate squirrel.Sqlizer, updatePtrs ...interface{}) func(o *Options) {
+	res := strings.Builder{}
+	parts := make([]interface{}, 0, 3)
+
+	res.WriteString("ON CONFLICT")
+
+	if len(conflictPtrs) > 0 {
+		res.WriteString(" (")
+
+		for i, ptr := range conflictPtrs {
+			if i != 0 {
+				res.WriteString(", ")
+			}
+
+			res.WriteString(r.Ref(NoTable(ptr)))
+		}
+
+		res.WriteString(")")
+
+		if predicate != nil {
+			res.WriteString(" WHERE ")
+			parts = append(parts, res.String(), predicate)
+			res.Reset()
+		}
+	}
+
+	if len(updatePtrs) == 0 {
+		res.WriteString(" DO NOTHING")
+	} else {
+		res.WriteString(" DO UPDATE SET ")
+
+		for i, ptr := range updatePtrs {
+			if i != 0 {
+				res.WriteString(", ")
+			}
+
+			col := r.Ref(NoTable(ptr))
+			res.WriteString(col + " = EXCLUDED." + col)
+		}
+	}
+
+	clause := squirrel.ConcatExpr(append(parts, res.String())...)
+
+	return func(o *Options) {
+		o.OnConflict = clause
+	}
+}
+
+// RenameColumns makes a Mapper option to use different column names for fields in a statement.
+//
+// Field pointers need to be added first with AddTableAlias in the Referencer.
+// New name replaces the name from field tag, other options (e.g. Columns, UseDefault) still refer
+// to the name from field tag, PrepareColumn is applied to the new name.
+// Renamed columns in SELECT are aliased with names from field tags to allow scanning.
+//
+//	sm.Insert(q, row, sqluct.RenameColumns(map[interface{}]string{&row.Name: "full_name"}, rf))
+//
+// It panics if pointer is unknown.
+func RenameColumns(renames map[interface{}]string, r *Referencer) func(o *Options) {
+	cols := make(map[string]string, len(renames))
+
+	for ptr, name := range renames {
+		cols[r.Col(ptr)] = name
+	}
+
+	return func(o *Options) {
+		if o.RenameColumns == nil {
+			o.RenameColumns = make(map[string]string, len(cols))
+		}
+
+		for k, v := range cols {
+			o.RenameColumns[k] = v
+		}
+	}
+}
+
 // QuotedNoTable is a container of field pointer that should be referenced without table.
 type QuotedNoTable struct {
 	ptr interface{}
@@ -133,6 +274,33 @@ func (r *Referencer) AddTableAlias(rowStructPtr interface{}, alias string) {
 		panic(err)
 	}
 
+	r.addAlias(rowStructPtr, alias, f)
+}
+
+// AddSubqueryAlias creates string references for row pointer and its fields as columns of a derived table,
+// e.g. `FROM (SELECT ...) AS sub`.
+//
+// Derived table exposes columns as they are named in result of subquery made with Mapper.Select
+// (or SelectStmt), so columns of inline structures are referenced by their aliases (e.g. `sub."addr.street"`)
+// instead of prefixed column names.
+//
+//	rf.AddSubqueryAlias(sub, "sub")
+//	q := rf.Select(squirrel.Select(), sub).FromSelect(st.SelectStmt("users", sub), rf.Fmt("%s", sub))
+func (r *Referencer) AddSubqueryAlias(rowStructPtr interface{}, alias string) {
+	f, err := mapper(r.Mapper).selectedColumnNames(rowStructPtr)
+	if err != nil {
+		panic(err)
+	}
+
+	r.addAlias(rowStructPtr, alias, f)
+}
+
+func (r *Referencer) addAlias(rowStructPtr interface{}, alias string, f map[interface{}]string) {
+	r.checkIdentifier(alias)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if r.refs == nil {
 		r.refs = make(map[interface{}]Quoted, len(f)+1)
 	}
@@ -147,13 +315,22 @@ func (r *Referencer) AddTableAlias(rowStructPtr interface{}, alias string) {
 
 	if r.structRefs == nil {
 		r.structRefs = make(map[interface{}][]string)
+		r.structNames = make(map[interface{}][]string)
+		r.structFields = make(map[interface{}]map[interface{}]string)
+		r.aliases = make(map[interface{}]string)
 	}
 
+	r.quoteAlias(rowStructPtr, alias, f)
+}
+
+// quoteAlias builds quoted references of row structure and its fields, it must be called with write lock.
+func (r *Referencer) quoteAlias(rowStructPtr interface{}, alias string, f map[interface{}]string) {
 	if alias != "" {
 		r.refs[rowStructPtr] = r.Q(alias)
 	}
 
 	refs := make([]string, 0, len(f))
+	cols := make(map[string]string, len(f))
 
 	for ptr, fieldName := range f {
 		var ref Quoted
@@ -165,6 +342,7 @@ func (r *Referencer) AddTableAlias(rowStructPtr interface{}, alias string) {
 		}
 
 		refs = append(refs, string(ref))
+		cols[string(ref)] = fieldName
 		r.refs[ptr] = ref
 		r.quotedCols[ptr] = r.Q(fieldName)
 		r.columnNames[ptr] = fieldName
@@ -172,7 +350,50 @@ func (r *Referencer) AddTableAlias(rowStructPtr interface{}, alias string) {
 
 	sort.Strings(refs)
 
+	structCols := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		structCols = append(structCols, cols[ref])
+	}
+
 	r.structRefs[rowStructPtr] = refs
+	r.structNames[rowStructPtr] = structCols
+	r.structFields[rowStructPtr] = f
+	r.aliases[rowStructPtr] = alias
+}
+
+// SetQuoter replaces IdentifierQuoter and rebuilds references of row structures added earlier.
+//
+// References are quoted when row structure is added, so changing IdentifierQuoter field afterwards
+// does not affect them. SetQuoter should not be called concurrently with other methods,
+// as IdentifierQuoter is read without lock.
+func (r *Referencer) SetQuoter(quoter func(tableAndColumn ...string) string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.IdentifierQuoter = quoter
+
+	for rowStructPtr, f := range r.structFields {
+		r.quoteAlias(rowStructPtr, r.aliases[rowStructPtr], f)
+	}
+}
+
+// checkIdentifier panics if table name or alias is not a plain identifier and can not be rendered safely without quoter.
+func (r *Referencer) checkIdentifier(name string) {
+	if name == "" || r.IdentifierQuoter != nil || r.RawIdentifiers {
+		return
+	}
+
+	for _, part := range strings.Split(name, ".") {
+		valid := part != "" && isIdentByte(part[0]) && (part[0] < '0' || part[0] > '9')
+
+		for i := 0; valid && i < len(part); i++ {
+			valid = isIdentByte(part[i]) || part[i] == '$'
+		}
+
+		if !valid {
+			panic(fmt.Sprintf("invalid identifier %q, please use IdentifierQuoter or enable RawIdentifiers", name))
+		}
+	}
 }
 
 // Quoted is a string that can be interpolated into an SQL statement as is.
@@ -204,6 +425,9 @@ func (r *Referencer) ref(ptr interface{}) (string, error) {
 		return string(q), nil
 	}
 
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	refs := r.refs
 
 	if nt, ok := ptr.(QuotedNoTable); ok {
@@ -236,12 +460,158 @@ func (r *Referencer) Refs(ptrs ...interface{}) []string {
 	return args
 }
 
+// Select adds column references of field pointers to select query builder.
+//
+// Row structure pointer stands for all columns of the structure (same as Cols).
+//
+//	q = rf.Select(squirrel.Select(), order, &user.Name).From(rf.Ref(order)).Join(...)
+//
+// It panics if pointer is unknown.
+func (r *Referencer) Select(qb squirrel.SelectBuilder, ptrs ...interface{}) squirrel.SelectBuilder {
+	cols := make([]string, 0, len(ptrs))
+
+	for i, ptr := range ptrs {
+		if structCols, found := r.structCols(ptr); found {
+			cols = append(cols, structCols...)
+
+			continue
+		}
+
+		ref, err := r.ref(ptr)
+		if err != nil {
+			panic(fmt.Errorf("%w at position %d", err, i))
+		}
+
+		cols = append(cols, ref)
+	}
+
+	return qb.Columns(cols...)
+}
+
+// From sets FROM clause of select query builder with quoted table name and optional alias.
+//
+// Row structure pointer, if not nil, is registered with AddTableAlias using alias (or table name if alias is empty),
+// so that its fields can be referenced in the rest of the statement.
+//
+//	q = rf.From(rf.Select(squirrel.Select(), order), order, "orders", "o")
+//
+// Resulting builder is a regular squirrel.SelectBuilder, calling its own From later replaces the clause.
+func (r *Referencer) From(qb squirrel.SelectBuilder, rowStructPtr interface{}, tableName, alias string) squirrel.SelectBuilder {
+	return qb.From(r.table(rowStructPtr, tableName, alias))
+}
+
+// Join adds JOIN clause of select query builder with quoted table name, optional alias and ON condition.
+//
+// Row structure pointer, if not nil, is registered same way as in From before ON condition is formatted.
+// Condition is formatted with Expr, so it can contain references and bind arguments.
+//
+//	q = rf.Join(q, user, "users", "u", "%s = %s AND %s = ?", &user.ID, &order.UserID, &user.Status, "active")
+//
+// Resulting builder is a regular squirrel.SelectBuilder, so squirrel joins can be added along.
+func (r *Referencer) Join(
+	qb squirrel.SelectBuilder,
+	rowStructPtr interface{},
+	tableName, alias string,
+	on string, ptrsOrArgs ...interface{},
+) squirrel.SelectBuilder {
+	return r.join(qb, "JOIN", rowStructPtr, tableName, alias, on, ptrsOrArgs)
+}
+
+// LeftJoin adds LEFT JOIN clause of select query builder, see Join for details.
+func (r *Referencer) LeftJoin(
+	qb squirrel.SelectBuilder,
+	rowStructPtr interface{},
+	tableName, alias string,
+	on string, ptrsOrArgs ...interface{},
+) squirrel.SelectBuilder {
+	return r.join(qb, "LEFT JOIN", rowStructPtr, tableName, alias, on, ptrsOrArgs)
+}
+
+func (r *Referencer) join(
+	qb squirrel.SelectBuilder,
+	joinType string,
+	rowStructPtr interface{},
+	tableName, alias string,
+	on string, ptrsOrArgs []interface{},
+) squirrel.SelectBuilder {
+	table := strings.ReplaceAll(r.table(rowStructPtr, tableName, alias), "%", "%%")
+
+	return qb.JoinClause(r.Expr(joinType+" "+table+" ON "+on, ptrsOrArgs...))
+}
+
+// table returns quoted table name with optional alias and registers row structure pointer.
+func (r *Referencer) table(rowStructPtr interface{}, tableName, alias string) string {
+	r.checkIdentifier(tableName)
+	r.checkIdentifier(alias)
+
+	if rowStructPtr != nil {
+		if alias != "" {
+			r.AddTableAlias(rowStructPtr, alias)
+		} else {
+			r.AddTableAlias(rowStructPtr, tableName)
+		}
+	}
+
+	if alias == "" {
+		return string(r.Q(tableName))
+	}
+
+	return string(r.Q(tableName)) + " AS " + string(r.Q(alias))
+}
+
+// SelectJoined adds columns of row structures to select query builder aliased with table alias prefix,
+// e.g. `"u"."id" AS "u.id"`, so that rows of joined tables with overlapping column names can be scanned
+// into a structure with row fields tagged with table aliases.
+//
+//	type UserRole struct {
+//		User User `db:"u"`
+//		Role Role `db:"r"`
+//	}
+//
+//	q = rf.SelectJoined(squirrel.Select(), user, role).From(...).Join(...)
+//
+// It panics if row structure pointer is unknown.
+func (r *Referencer) SelectJoined(qb squirrel.SelectBuilder, rowStructPtrs ...interface{}) squirrel.SelectBuilder {
+	sm := mapper(r.Mapper)
+
+	for i, ptr := range rowStructPtrs {
+		r.mu.RLock()
+		refs, found := r.structRefs[ptr]
+		cols := r.structNames[ptr]
+		alias := r.aliases[ptr]
+		r.mu.RUnlock()
+
+		if !found {
+			panic(fmt.Errorf("%w at position %d", errUnknownFieldOrRow, i))
+		}
+
+		aliased := make([]string, 0, len(refs))
+
+		for j, ref := range refs {
+			label := cols[j]
+			if alias != "" {
+				label = alias + "." + label
+			}
+
+			aliased = append(aliased, ref+" AS "+sm.quoteAlias(label))
+		}
+
+		qb = qb.Columns(aliased...)
+	}
+
+	return qb
+}
+
 // Col returns unescaped column name for field pointer that was previously added with AddTableAlias.
 //
 // It panics if pointer is unknown.
 // Might be used with Options.Columns.
 func (r *Referencer) Col(ptr interface{}) string {
-	if col, found := r.columnNames[ptr]; found {
+	r.mu.RLock()
+	col, found := r.columnNames[ptr]
+	r.mu.RUnlock()
+
+	if found {
 		return col
 	}
 
@@ -250,11 +620,22 @@ func (r *Referencer) Col(ptr interface{}) string {
 
 // Fmt formats according to a format specified replacing ptrs with their reference strings where possible.
 //
-// It panics if pointer is unknown or is not a Quoted string.
+// Pointers, Quoted and NoTable arguments are replaced with references, other arguments are formatted as is,
+// so they can be used with verbs like %d for safe literals.
+//
+//	rf.Fmt("%s > %d", &row.Amount, 100)
+//
+// It panics if pointer is unknown.
 func (r *Referencer) Fmt(format string, ptrs ...interface{}) string {
 	args := make([]interface{}, 0, len(ptrs))
 
 	for i, fieldPtr := range ptrs {
+		if !isRef(fieldPtr) {
+			args = append(args, fieldPtr)
+
+			continue
+		}
+
 		ref, err := r.ref(fieldPtr)
 		if err != nil {
 			panic(fmt.Errorf("%w at position %d", err, i))
@@ -266,16 +647,297 @@ func (r *Referencer) Fmt(format string, ptrs ...interface{}) string {
 	return fmt.Sprintf(format, args...)
 }
 
+// Expr formats SQL fragment with references and returns it as squirrel.Sqlizer with bind arguments.
+//
+// Non-nil pointers, Quoted and NoTable arguments are replaced with references in format verbs,
+// other arguments (including nil) are bind arguments for `?` placeholders in order of appearance.
+//
+//	q.Where(rf.Expr("%s > ? AND %s IS NOT NULL", &row.Amount, 0, &row.DeletedAt))
+//
+// It panics if pointer is unknown.
+func (r *Referencer) Expr(format string, ptrsOrArgs ...interface{}) squirrel.Sqlizer {
+	var refs, args []interface{}
+
+	for i, arg := range ptrsOrArgs {
+		if arg == nil || !isRef(arg) {
+			args = append(args, arg)
+
+			continue
+		}
+
+		ref, err := r.ref(arg)
+		if err != nil {
+			panic(fmt.Errorf("%w at position %d", err, i))
+		}
+
+		refs = append(refs, ref)
+	}
+
+	return squirrel.Expr(fmt.Sprintf(format, refs...), args...)
+}
+
+func isRef(arg interface{}) bool {
+	switch arg.(type) {
+	case nil, Quoted, QuotedNoTable:
+		return true
+	}
+
+	return reflect.TypeOf(arg).Kind() == reflect.Ptr
+}
+
 // Cols returns column references of a row structure.
 func (r *Referencer) Cols(ptr interface{}) []string {
-	if cols, found := r.structRefs[ptr]; found {
+	if cols, found := r.structCols(ptr); found {
 		return cols
 	}
 
 	panic(errUnknownFieldOrRow)
 }
 
+func (r *Referencer) structCols(ptr interface{}) ([]string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cols, found := r.structRefs[ptr]
+
+	return cols, found
+}
+
+// ColsString returns comma-separated column references of a row structure, e.g. "t.a, t.b, t.c".
+func (r *Referencer) ColsString(ptr interface{}) string {
+	return strings.Join(r.Cols(ptr), ", ")
+}
+
+// Over returns window function expression with PARTITION BY and ORDER BY references,
+// e.g. "ROW_NUMBER() OVER (PARTITION BY o.user_id ORDER BY o.created_at DESC)".
+//
+// Field pointers and Quoted values are replaced with references, strings are used as is,
+// so that ordering direction can be added with Fmt.
+//
+//	rn := rf.Over("ROW_NUMBER()", []interface{}{&o.UserID}, []interface{}{rf.Fmt("%s DESC", &o.CreatedAt)})
+//	q := st.SelectStmt("orders", o, rf.ColumnsOf(o), sqluct.ExtraColumns(rn+" AS rn"))
+//
+// Window functions are supported by Postgres, MySQL 8+ and SQLite 3.25+.
+//
+// It panics if pointer is unknown.
+func (r *Referencer) Over(expr string, partitionBy []interface{}, orderBy []interface{}) string {
+	res := strings.Builder{}
+	res.WriteString(expr + " OVER (")
+
+	if len(partitionBy) > 0 {
+		res.WriteString("PARTITION BY " + strings.Join(r.windowRefs(partitionBy), ", "))
+	}
+
+	if len(orderBy) > 0 {
+		if len(partitionBy) > 0 {
+			res.WriteString(" ")
+		}
+
+		res.WriteString("ORDER BY " + strings.Join(r.windowRefs(orderBy), ", "))
+	}
+
+	res.WriteString(")")
+
+	return res.String()
+}
+
+// windowRefs returns references of field pointers and raw strings of window clause.
+func (r *Referencer) windowRefs(items []interface{}) []string {
+	refs := make([]string, 0, len(items))
+
+	for i, item := range items {
+		if s, ok := item.(string); ok {
+			refs = append(refs, s)
+
+			continue
+		}
+
+		ref, err := r.ref(item)
+		if err != nil {
+			panic(fmt.Errorf("%w at position %d", err, i))
+		}
+
+		refs = append(refs, ref)
+	}
+
+	return refs
+}
+
+// JSONAgg returns Postgres json_agg expression that aggregates columns of row structure into JSON array of objects.
+//
+// Object keys are JSON names of fields (from `json` tag or field name), so that result can be scanned
+// into a JSON[[]Child] field of parent row to load parent with children in a single query.
+//
+//	q := rf.Select(squirrel.Select(), parent).
+//		Column(rf.JSONAgg(child) + " AS children").
+//		From(rf.Fmt("%s AS %s", rf.Q("parents"), parent)).
+//		LeftJoin(rf.Fmt("%s AS %s ON %s = %s", rf.Q("children"), child, &child.ParentID, &parent.ID)).
+//		GroupBy(rf.Ref(&parent.ID))
+//
+// It panics if row structure pointer is unknown.
+func (r *Referencer) JSONAgg(rowStructPtr interface{}) string {
+	names, err := mapper(r.Mapper).findNames(rowStructPtr, nil, jsonFieldName)
+	if err != nil {
+		panic(err)
+	}
+
+	type kv struct {
+		key string
+		ref Quoted
+	}
+
+	fields := make([]kv, 0, len(names))
+
+	r.mu.RLock()
+
+	if _, found := r.structRefs[rowStructPtr]; !found {
+		r.mu.RUnlock()
+		panic("row structure pointer needs to be added first with AddTableAlias")
+	}
+
+	for ptr, name := range names {
+		if ref, found := r.refs[ptr]; found && name != "" {
+			fields = append(fields, kv{key: name, ref: ref})
+		}
+	}
+
+	r.mu.RUnlock()
+
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].ref < fields[j].ref
+	})
+
+	res := strings.Builder{}
+	res.WriteString("json_agg(json_build_object(")
+
+	for i, f := range fields {
+		if i != 0 {
+			res.WriteString(", ")
+		}
+
+		res.WriteString("'" + strings.ReplaceAll(f.key, "'", "''") + "', " + string(f.ref))
+	}
+
+	res.WriteString("))")
+
+	return res.String()
+}
+
+// jsonFieldName returns name of field in JSON, empty for fields excluded from JSON.
+func jsonFieldName(fi *reflectx.FieldInfo) string {
+	name := strings.SplitN(fi.Field.Tag.Get("json"), ",", 2)[0]
+
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return fi.Field.Name
+	}
+
+	return name
+}
+
 // Eq is a shortcut for squirrel.Eq{r.Ref(ptr): val}.
 func (r *Referencer) Eq(ptr interface{}, val interface{}) squirrel.Eq {
 	return squirrel.Eq{r.Ref(ptr): val}
 }
+
+// WhereEq maps struct values as conditions to squirrel.Eq with columns prefixed by table alias of row structure.
+//
+// It is a shortcut for Mapper.WhereEq(conditions, r.ColumnsOf(rowStructPtr), options...).
+//
+//	q.Where(rf.WhereEq(order, Order{UserID: 123}, sqluct.SkipZeroValues))
+//
+// It panics if row structure pointer is unknown.
+func (r *Referencer) WhereEq(rowStructPtr interface{}, conditions interface{}, options ...func(*Options)) squirrel.Eq {
+	return mapper(r.Mapper).WhereEq(conditions, append([]func(*Options){r.ColumnsOf(rowStructPtr)}, options...)...)
+}
+
+// Set maps field pointer and value as column assignment, result can be used with squirrel.UpdateBuilder.SetMap.
+//
+// Column is referenced without table prefix.
+//
+//	q.SetMap(rf.Set(&row.Name, "John"))
+//
+// It panics if pointer is unknown.
+func (r *Referencer) Set(ptr interface{}, val interface{}) map[string]interface{} {
+	return map[string]interface{}{r.Ref(NoTable(ptr)): val}
+}
+
+// Incr maps field pointer and delta as column increment, result can be used with squirrel.UpdateBuilder.Set.
+//
+// Column is referenced without table prefix, negative delta decrements the column.
+//
+//	q.Set(rf.Incr(&row.Views, 1)) // SET views = views + ?
+//
+// It panics if pointer is unknown.
+func (r *Referencer) Incr(ptr interface{}, delta interface{}) (string, interface{}) {
+	col := r.Ref(NoTable(ptr))
+
+	return col, squirrel.Expr(col+" + ?", delta)
+}
+
+// CTE is a WITH clause of common table expressions, it can be used as a statement prefix.
+//
+//	q.PrefixExpr(rf.WithCTE(cte, "cte", body))
+type CTE struct {
+	r      *Referencer
+	names  []Quoted
+	bodies []interface{}
+}
+
+// WithCTE creates a WITH clause of common table expression and registers its name as an alias of row structure.
+//
+// Columns of CTE can then be referenced with field pointers of rowStructPtr.
+// Placeholders of squirrel builders in body are formatted together with the outer statement.
+func (r *Referencer) WithCTE(rowStructPtr interface{}, name string, body ToSQL) CTE {
+	return CTE{r: r}.WithCTE(rowStructPtr, name, body)
+}
+
+// WithCTE adds another common table expression to WITH clause.
+func (c CTE) WithCTE(rowStructPtr interface{}, name string, body ToSQL) CTE {
+	c.r.AddTableAlias(rowStructPtr, name)
+
+	c.names = append(c.names[:len(c.names):len(c.names)], c.r.Q(name))
+	c.bodies = append(c.bodies[:len(c.bodies):len(c.bodies)], rawPlaceholders(body))
+
+	return c
+}
+
+// rawPlaceholders disables placeholder formatting of nested squirrel builders.
+func rawPlaceholders(qb ToSQL) ToSQL {
+	switch b := qb.(type) {
+	case squirrel.SelectBuilder:
+		return b.PlaceholderFormat(squirrel.Question)
+	case squirrel.InsertBuilder:
+		return b.PlaceholderFormat(squirrel.Question)
+	case squirrel.UpdateBuilder:
+		return b.PlaceholderFormat(squirrel.Question)
+	case squirrel.DeleteBuilder:
+		return b.PlaceholderFormat(squirrel.Question)
+	}
+
+	return qb
+}
+
+// ToSql renders WITH clause.
+func (c CTE) ToSql() (string, []interface{}, error) { //nolint // Method name matches ext. implementation.
+	if len(c.names) == 0 {
+		return "", nil, nil
+	}
+
+	res := strings.Builder{}
+
+	res.WriteString("WITH ")
+
+	for i, name := range c.names {
+		if i != 0 {
+			res.WriteString(", ")
+		}
+
+		res.WriteString(string(name))
+		res.WriteString(" AS (?)")
+	}
+
+	return squirrel.Expr(res.String(), c.bodies...).ToSql()
+}
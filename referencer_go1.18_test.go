@@ -0,0 +1,68 @@
+//go:build go1.18
+// +build go1.18
+
+package sqluct_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bool64/sqluct"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferencer_JSONAgg(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	type Item struct {
+		OrderID int    `db:"order_id" json:"-"`
+		SKU     string `db:"sku" json:"sku"`
+		Qty     int    `db:"qty"`
+	}
+
+	type Order struct {
+		ID int `db:"id"`
+	}
+
+	type OrderWithItems struct {
+		Order
+		Items sqluct.JSON[[]Item] `db:"items"`
+	}
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	st.IdentifierQuoter = sqluct.QuoteANSI
+
+	rf := st.MakeReferencer()
+	o := sqluct.Alias[Order](rf, "o")
+	i := sqluct.Alias[Item](rf, "i")
+
+	assert.Equal(t, `json_agg(json_build_object('Qty', "i"."qty", 'sku', "i"."sku"))`, rf.JSONAgg(i))
+
+	q := rf.Select(st.QueryBuilder().Select(), o).
+		Column(rf.JSONAgg(i) + " AS items").
+		From(rf.Fmt("%s AS %s", rf.Q("orders"), o)).
+		Join(rf.Fmt("%s AS %s ON %s = %s", rf.Q("items"), i, &i.OrderID, &o.ID)).
+		GroupBy(rf.Ref(&o.ID))
+
+	mock.ExpectQuery(`SELECT "o"."id", json_agg(json_build_object('Qty', "i"."qty", 'sku', "i"."sku")) AS items ` +
+		`FROM "orders" AS "o" JOIN "items" AS "i" ON "i"."order_id" = "o"."id" GROUP BY "o"."id"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "items"}).
+			AddRow(1, `[{"Qty":2,"sku":"a"},{"Qty":1,"sku":"b"}]`))
+
+	var rows []OrderWithItems
+
+	require.NoError(t, st.Select(context.Background(), q, &rows))
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, []OrderWithItems{{
+		Order: Order{ID: 1},
+		Items: sqluct.JSON[[]Item]{Val: []Item{{SKU: "a", Qty: 2}, {SKU: "b", Qty: 1}}},
+	}}, rows)
+
+	assert.Panics(t, func() {
+		rf.JSONAgg(&Item{})
+	})
+}
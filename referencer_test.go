@@ -1,7 +1,9 @@
 package sqluct_test
 
 import (
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/bool64/sqluct"
@@ -282,3 +284,506 @@ func TestNoTable(t *testing.T) {
 	assert.Equal(t, "`first_name`", ref.Ref(sqluct.NoTable(&row.FirstName)))
 	assert.Equal(t, "`users`.`first_name`", ref.Ref(&row.FirstName))
 }
+
+func TestReferencer_WithCTE(t *testing.T) {
+	rf := sqluct.Referencer{}
+	rf.IdentifierQuoter = sqluct.QuoteANSI
+
+	type Order struct {
+		ID     int `db:"id"`
+		UserID int `db:"user_id"`
+		Amount int `db:"amount"`
+	}
+
+	type Total struct {
+		UserID int `db:"user_id"`
+		Amount int `db:"amount"`
+	}
+
+	type Big struct {
+		UserID int `db:"user_id"`
+	}
+
+	o := &Order{}
+	rf.AddTableAlias(o, "orders")
+
+	ps := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+
+	tot := &Total{}
+	big := &Big{}
+
+	cte := rf.WithCTE(tot, "totals", ps.Select(rf.Ref(&o.UserID), "SUM("+rf.Ref(&o.Amount)+") AS "+rf.Ref(sqluct.NoTable(&o.Amount))).
+		From(rf.Ref(o)).Where(squirrel.Gt{rf.Ref(&o.Amount): 10}).GroupBy(rf.Ref(&o.UserID)))
+	cte = cte.WithCTE(big, "big", ps.Select(rf.Ref(&tot.UserID)).
+		From(rf.Ref(tot)).Where(squirrel.Gt{rf.Ref(&tot.Amount): 100}))
+
+	assert.Equal(t, `"totals"`, rf.Ref(tot))
+	assert.Equal(t, `"big"."user_id"`, rf.Ref(&big.UserID))
+
+	q := ps.Select(rf.Cols(o)...).PrefixExpr(cte).
+		From(rf.Ref(o)).
+		InnerJoin(rf.Fmt("%s ON %s = %s", big, &big.UserID, &o.UserID)).
+		Where(squirrel.Eq{rf.Ref(&o.ID): 5})
+
+	stmt, args, err := q.ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, `WITH "totals" AS (SELECT "orders"."user_id", SUM("orders"."amount") AS "amount" FROM "orders" WHERE "orders"."amount" > $1 GROUP BY "orders"."user_id"), `+
+		`"big" AS (SELECT "totals"."user_id" FROM "totals" WHERE "totals"."amount" > $2) `+
+		`SELECT "orders"."amount", "orders"."id", "orders"."user_id" FROM "orders" `+
+		`INNER JOIN "big" ON "big"."user_id" = "orders"."user_id" WHERE "orders"."id" = $3`, stmt)
+	assert.Equal(t, []interface{}{10, 100, 5}, args)
+
+	stmt, args, err = sqluct.CTE{}.ToSql()
+	require.NoError(t, err)
+	assert.Empty(t, stmt)
+	assert.Empty(t, args)
+}
+
+func TestReferencer_UseDefault(t *testing.T) {
+	type Order struct {
+		ID        int       `db:"id"`
+		Amount    int       `db:"amount"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+
+	s := sqluct.Storage{}
+	s.Mapper = &sqluct.Mapper{Dialect: sqluct.DialectPostgres}
+	s.IdentifierQuoter = sqluct.QuoteANSI
+
+	rf := s.MakeReferencer()
+	o := &Order{}
+	rf.AddTableAlias(o, "orders")
+
+	ts := time.Now()
+
+	query, args, err := s.InsertStmt("orders", Order{ID: 1, Amount: 2, CreatedAt: ts}, rf.UseDefault(&o.CreatedAt)).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, `INSERT INTO "orders" ("id","amount","created_at") VALUES ($1,$2,DEFAULT)`, query)
+	assert.Equal(t, []interface{}{1, 2}, args)
+
+	query, args, err = s.InsertStmt("orders", []Order{{ID: 1, Amount: 2}, {ID: 3, Amount: 4}}, rf.UseDefault(&o.ID, &o.CreatedAt)).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, `INSERT INTO "orders" ("id","amount","created_at") VALUES (DEFAULT,$1,DEFAULT),(DEFAULT,$2,DEFAULT)`, query)
+	assert.Equal(t, []interface{}{2, 4}, args)
+
+	assert.Panics(t, func() {
+		rf.UseDefault(&ts)
+	})
+}
+
+func TestReferencer_ValueExpr(t *testing.T) {
+	type Order struct {
+		ID        int       `db:"id"`
+		Amount    int       `db:"amount"`
+		Status    string    `db:"status"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+
+	s := sqluct.Storage{}
+	s.Mapper = &sqluct.Mapper{Dialect: sqluct.DialectPostgres}
+	s.IdentifierQuoter = sqluct.QuoteANSI
+
+	rf := s.MakeReferencer()
+	o := &Order{}
+	rf.AddTableAlias(o, "orders")
+
+	query, args, err := s.InsertStmt("orders", Order{ID: 1, Amount: 2, Status: "new"},
+		rf.ValueExpr(&o.Amount, "2 * 3"), rf.ValueExpr(&o.CreatedAt, "now()")).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, `INSERT INTO "orders" ("id","amount","status","created_at") VALUES ($1,2 * 3,$2,now())`, query)
+	assert.Equal(t, []interface{}{1, "new"}, args)
+
+	query, args, err = s.InsertStmt("orders", []Order{{ID: 1, Status: "a"}, {ID: 3, Status: "b"}},
+		rf.ValueExpr(&o.CreatedAt, "now()"), rf.UseDefault(&o.Amount)).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, `INSERT INTO "orders" ("id","amount","status","created_at") `+
+		`VALUES ($1,DEFAULT,$2,now()),($3,DEFAULT,$4,now())`, query)
+	assert.Equal(t, []interface{}{1, "a", 3, "b"}, args)
+}
+
+func TestReferencer_OnConflict(t *testing.T) {
+	type User struct {
+		ID        int        `db:"id,omitempty"`
+		Email     string     `db:"email"`
+		Name      string     `db:"name"`
+		DeletedAt *time.Time `db:"deleted_at"`
+	}
+
+	s := sqluct.Storage{}
+	s.Mapper = &sqluct.Mapper{Dialect: sqluct.DialectPostgres}
+	s.IdentifierQuoter = sqluct.QuoteANSI
+
+	rf := s.MakeReferencer()
+	u := &User{}
+	rf.AddTableAlias(u, "users")
+
+	assertStatementArgs(t, `INSERT INTO "users" ("email","name","deleted_at") VALUES ($1,$2,$3) `+
+		`ON CONFLICT ("email") WHERE "deleted_at" IS NULL DO UPDATE SET "name" = EXCLUDED."name"`,
+		[]interface{}{"a@b.c", "John", (*time.Time)(nil)},
+		s.InsertStmt("users", User{Email: "a@b.c", Name: "John"},
+			rf.OnConflict([]interface{}{&u.Email}, rf.Expr("%s IS NULL", sqluct.NoTable(&u.DeletedAt)), &u.Name)))
+
+	assertStatementArgs(t, `INSERT INTO "users" ("email","name","deleted_at") VALUES ($1,$2,$3) `+
+		`ON CONFLICT ("email") WHERE "name" <> $4 DO NOTHING`,
+		[]interface{}{"a@b.c", "John", (*time.Time)(nil), ""},
+		s.InsertStmt("users", User{Email: "a@b.c", Name: "John"},
+			rf.OnConflict([]interface{}{&u.Email}, rf.Expr("%s <> ?", sqluct.NoTable(&u.Name), ""))))
+
+	assertStatement(t, `INSERT INTO "users" ("email","name","deleted_at") VALUES ($1,$2,$3) `+
+		`ON CONFLICT ("email", "name") DO NOTHING`,
+		s.InsertStmt("users", User{}, rf.OnConflict([]interface{}{&u.Email, &u.Name}, nil)))
+
+	assertStatement(t, `INSERT INTO "users" ("email","name","deleted_at") VALUES ($1,$2,$3) ON CONFLICT DO NOTHING`,
+		s.InsertStmt("users", User{}, rf.OnConflict(nil, nil)))
+
+	s.Mapper.Dialect = sqluct.DialectMySQL
+
+	assert.Panics(t, func() {
+		s.InsertStmt("users", User{}, rf.OnConflict(nil, nil))
+	})
+}
+
+func TestReferencer_Fmt_literals(t *testing.T) {
+	rf := sqluct.Referencer{}
+	rf.IdentifierQuoter = sqluct.QuoteANSI
+
+	type Order struct {
+		ID     int     `db:"id"`
+		Amount float64 `db:"amount"`
+	}
+
+	o := &Order{}
+	rf.AddTableAlias(o, "orders")
+
+	assert.Equal(t, `"orders"."amount" > 100 AND "orders"."id" IN (1, 2) LIMIT 10 -- 'note'`,
+		rf.Fmt("%s > %d AND %s IN (%v, %v) LIMIT %d -- %s", &o.Amount, 100, &o.ID, 1, 2, 10, sqluct.Quoted("'note'")))
+	assert.Equal(t, `"amount" = 1.50`, rf.Fmt("%s = %.2f", sqluct.NoTable(&o.Amount), 1.5))
+
+	assert.Panics(t, func() {
+		id := 1
+		rf.Fmt("%s = %d", &o.ID, &id)
+	})
+	assert.Panics(t, func() {
+		rf.Fmt("%s", nil)
+	})
+}
+
+func TestReferencer_Set(t *testing.T) {
+	type User struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	rf := sqluct.Referencer{}
+	rf.IdentifierQuoter = sqluct.QuoteANSI
+
+	u := &User{}
+	rf.AddTableAlias(u, "users")
+
+	assertStatementArgs(t, `UPDATE users SET "name" = ? WHERE "users"."id" = ?`, []interface{}{"John", 1},
+		squirrel.Update("users").SetMap(rf.Set(&u.Name, "John")).Where(rf.Eq(&u.ID, 1)))
+	assert.Panics(t, func() { rf.Set(&User{}, 1) })
+}
+
+func TestReferencer_Incr(t *testing.T) {
+	type Post struct {
+		ID    int `db:"id"`
+		Views int `db:"views"`
+		Stock int `db:"stock"`
+	}
+
+	rf := sqluct.Referencer{}
+	rf.IdentifierQuoter = sqluct.QuoteANSI
+
+	p := &Post{}
+	rf.AddTableAlias(p, "posts")
+
+	assertStatementArgs(t, `UPDATE posts SET "views" = "views" + ?, "stock" = "stock" + ? WHERE "posts"."id" = ?`,
+		[]interface{}{1, -2, 3},
+		squirrel.Update("posts").Set(rf.Incr(&p.Views, 1)).Set(rf.Incr(&p.Stock, -2)).Where(rf.Eq(&p.ID, 3)))
+	assert.Panics(t, func() { rf.Incr(&Post{}, 1) })
+}
+
+func TestReferencer_Over(t *testing.T) {
+	type Order struct {
+		ID        int       `db:"id"`
+		UserID    int       `db:"user_id"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+
+	rf := sqluct.Referencer{}
+	rf.IdentifierQuoter = sqluct.QuoteANSI
+
+	o := &Order{}
+	rf.AddTableAlias(o, "o")
+
+	rn := rf.Over("ROW_NUMBER()", []interface{}{&o.UserID}, []interface{}{rf.Fmt("%s DESC", &o.CreatedAt), &o.ID})
+	assert.Equal(t, `ROW_NUMBER() OVER (PARTITION BY "o"."user_id" ORDER BY "o"."created_at" DESC, "o"."id")`, rn)
+
+	st := sqluct.Storage{IdentifierQuoter: sqluct.QuoteANSI}
+
+	assertStatementArgs(t, `SELECT "o"."id", "o"."user_id", "o"."created_at", `+
+		`ROW_NUMBER() OVER (PARTITION BY "o"."user_id" ORDER BY "o"."created_at" DESC, "o"."id") AS rn FROM "orders"`, nil,
+		st.SelectStmt("orders", o, rf.ColumnsOf(o), sqluct.ExtraColumns(rn+" AS rn")))
+
+	assert.Equal(t, `SUM("o"."id") OVER (ORDER BY "o"."created_at")`,
+		rf.Over(rf.Fmt("SUM(%s)", &o.ID), nil, []interface{}{&o.CreatedAt}))
+	assert.Equal(t, `COUNT(*) OVER (PARTITION BY "o"."user_id")`, rf.Over("COUNT(*)", []interface{}{&o.UserID}, nil))
+	assert.Equal(t, `COUNT(*) OVER ()`, rf.Over("COUNT(*)", nil, nil))
+	assert.PanicsWithError(t, "unknown field or row or not a pointer at position 0", func() {
+		rf.Over("RANK()", []interface{}{&Order{}}, nil)
+	})
+}
+
+func TestReferencer_WhereEq(t *testing.T) {
+	type User struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	type Order struct {
+		ID     int `db:"id"`
+		UserID int `db:"user_id"`
+	}
+
+	rf := sqluct.Referencer{}
+	rf.IdentifierQuoter = sqluct.QuoteANSI
+
+	u := &User{}
+	o := &Order{}
+
+	rf.AddTableAlias(u, "u")
+	rf.AddTableAlias(o, "o")
+
+	assertStatementArgs(t, `SELECT "o"."id" FROM "orders" AS "o" JOIN "users" AS "u" ON "o"."user_id" = "u"."id" `+
+		`WHERE "o"."user_id" = ? AND "u"."name" = ?`,
+		[]interface{}{1, "John"},
+		squirrel.Select(rf.Ref(&o.ID)).From(`"orders" AS "o"`).
+			Join(`"users" AS "u" ON `+rf.Fmt("%s = %s", &o.UserID, &u.ID)).
+			Where(rf.WhereEq(o, Order{UserID: 1}, sqluct.SkipZeroValues)).
+			Where(rf.WhereEq(u, User{Name: "John"}, sqluct.SkipZeroValues)))
+
+	assert.Panics(t, func() { rf.WhereEq(&User{}, User{}) })
+}
+
+func TestReferencer_Select(t *testing.T) {
+	type User struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	type Order struct {
+		ID     int `db:"id"`
+		UserID int `db:"user_id"`
+	}
+
+	rf := sqluct.Referencer{}
+	u := &User{}
+	o := &Order{}
+
+	rf.AddTableAlias(u, "u")
+	rf.AddTableAlias(o, "o")
+
+	assertStatement(t, "SELECT o.id, o.user_id, u.name, name FROM orders o JOIN users u ON o.user_id = u.id",
+		rf.Select(squirrel.Select(), o, &u.Name, sqluct.NoTable(&u.Name)).
+			From("orders o").Join(rf.Fmt("users u ON %s = %s", &o.UserID, &u.ID)))
+
+	assert.PanicsWithError(t, "unknown field or row or not a pointer at position 1", func() {
+		rf.Select(squirrel.Select(), o, &User{})
+	})
+}
+
+func TestRenameColumns(t *testing.T) {
+	type User struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	sm := &sqluct.Mapper{}
+	rf := &sqluct.Referencer{Mapper: sm}
+	u := &User{}
+	rf.AddTableAlias(u, "u")
+
+	rename := sqluct.RenameColumns(map[interface{}]string{&u.Name: "full_name"}, rf)
+
+	assertStatementArgs(t, "INSERT INTO people (id,full_name) VALUES (?,?)", []interface{}{1, "John"},
+		sm.Insert(squirrel.Insert("people"), User{ID: 1, Name: "John"}, rename))
+	assertStatementArgs(t, "UPDATE people SET full_name = ? WHERE full_name = ?", []interface{}{"John", "Jon"},
+		sm.Update(squirrel.Update("people"), User{Name: "John"}, rename, sqluct.Columns("name")).
+			Where(sm.WhereEq(User{Name: "Jon"}, rename, sqluct.Columns("name"))))
+	assertStatement(t, `SELECT p.id, p.full_name AS "name" FROM people p`,
+		sm.Select(squirrel.Select(), User{}, rename, func(o *sqluct.Options) {
+			o.PrepareColumn = func(col string) string { return "p." + col }
+		}).From("people p"))
+
+	assert.Panics(t, func() { sqluct.RenameColumns(map[interface{}]string{&User{}: "a"}, rf) })
+}
+
+func TestReferencer_Expr(t *testing.T) {
+	type Row struct {
+		Amount    int        `db:"amount"`
+		DeletedAt *time.Time `db:"deleted_at"`
+	}
+
+	rf := sqluct.Referencer{IdentifierQuoter: sqluct.QuoteANSI}
+	r := &Row{}
+	rf.AddTableAlias(r, "r")
+
+	assertStatementArgs(t, `SELECT 1 FROM t WHERE "r"."amount" > ? AND "deleted_at" IS DISTINCT FROM ?`,
+		[]interface{}{10, nil},
+		squirrel.Select("1").From("t").Where(rf.Expr("%s > ? AND %s IS DISTINCT FROM ?",
+			&r.Amount, 10, sqluct.NoTable(&r.DeletedAt), nil)))
+
+	assert.PanicsWithError(t, "unknown field or row or not a pointer at position 1", func() {
+		rf.Expr("%s = %s", &r.Amount, &Row{})
+	})
+}
+
+func TestReferencer_From(t *testing.T) {
+	type Order struct {
+		ID     int `db:"id"`
+		UserID int `db:"user_id"`
+	}
+
+	type User struct {
+		ID     int    `db:"id"`
+		Status string `db:"status"`
+	}
+
+	rf := sqluct.Referencer{IdentifierQuoter: sqluct.QuoteANSI}
+	o := &Order{}
+	u := &User{}
+
+	q := rf.From(squirrel.Select(), o, "orders", "")
+	q = rf.Join(q, u, "users", "u", "%s = %s AND %s = ?", &u.ID, &o.UserID, &u.Status, "active")
+	q = rf.LeftJoin(q, nil, "user_roles", "", "user_roles.user_id = %s", &u.ID)
+	q = rf.Select(q, o, &u.Status)
+
+	stmt, args, err := q.ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT "orders"."id", "orders"."user_id", "u"."status" FROM "orders" `+
+		`JOIN "users" AS "u" ON "u"."id" = "orders"."user_id" AND "u"."status" = ? `+
+		`LEFT JOIN "user_roles" ON user_roles.user_id = "u"."id"`, stmt)
+	assert.Equal(t, []interface{}{"active"}, args)
+}
+
+func TestReferencer_AddSubqueryAlias(t *testing.T) {
+	type Address struct {
+		Street string `db:"street"`
+	}
+
+	type User struct {
+		ID   int     `db:"id"`
+		Addr Address `db:"addr,inline"`
+	}
+
+	sm := sqluct.Mapper{}
+	rf := sqluct.Referencer{Mapper: &sm, IdentifierQuoter: sqluct.QuoteANSI}
+	u := &User{}
+	rf.AddSubqueryAlias(u, "sub")
+
+	sub := sm.Select(squirrel.Select(), u).From("users").Where(squirrel.Gt{"id": 10})
+
+	q := rf.Select(squirrel.Select(), u).
+		FromSelect(sub, rf.Ref(u)).
+		Where(rf.Fmt("%s = ?", &u.Addr.Street), "Main")
+
+	stmt, args, err := q.ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT "sub"."addr.street", "sub"."id" `+
+		`FROM (SELECT id, addr_street AS "addr.street" FROM users WHERE id > ?) AS "sub" `+
+		`WHERE "sub"."addr.street" = ?`, stmt)
+	assert.Equal(t, []interface{}{10, "Main"}, args)
+	assert.Equal(t, "addr.street", rf.Col(&u.Addr.Street))
+}
+
+func TestReferencer_SetQuoter(t *testing.T) {
+	type User struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	rf := sqluct.Referencer{}
+	u := &User{}
+	rf.AddTableAlias(u, "u")
+
+	assert.Equal(t, "u.name", rf.Ref(&u.Name))
+
+	rf.SetQuoter(sqluct.QuoteANSI)
+
+	assert.Equal(t, `"u"`, rf.Ref(u))
+	assert.Equal(t, `"u"."name"`, rf.Ref(&u.Name))
+	assert.Equal(t, `"name"`, rf.Ref(sqluct.NoTable(&u.Name)))
+	assert.Equal(t, `"u"."id", "u"."name"`, rf.ColsString(u))
+
+	rf.SetQuoter(sqluct.QuoteBackticks)
+
+	assertStatement(t, "SELECT `u`.`id`, `u`.`name` FROM users AS `u` WHERE `u`.`id` = 1",
+		rf.Select(squirrel.Select(), u).From("users AS "+rf.Ref(u)).Where(rf.Fmt("%s = 1", &u.ID)))
+}
+
+func TestReferencer_RawIdentifiers(t *testing.T) {
+	type User struct {
+		ID int `db:"id"`
+	}
+
+	rf := sqluct.Referencer{}
+
+	assert.NotPanics(t, func() {
+		rf.AddTableAlias(&User{}, "u_1")
+		rf.From(squirrel.Select(), &User{}, "public.users", "")
+	})
+
+	assert.PanicsWithValue(t, `invalid identifier "u; DROP TABLE users", please use IdentifierQuoter or enable RawIdentifiers`,
+		func() { rf.AddTableAlias(&User{}, "u; DROP TABLE users") })
+	assert.Panics(t, func() { rf.From(squirrel.Select(), nil, "users u", "") })
+	assert.PanicsWithValue(t, `invalid identifier "u; DROP TABLE users", please use IdentifierQuoter or enable RawIdentifiers`,
+		func() { rf.From(squirrel.Select(), nil, "users", "u; DROP TABLE users") })
+	assert.Panics(t, func() { rf.Join(squirrel.Select(), nil, "roles", "r--", "TRUE") })
+	assert.Panics(t, func() { rf.AddTableAlias(&User{}, "1u") })
+	assert.Panics(t, func() { rf.AddTableAlias(&User{}, "public.") })
+
+	rf.RawIdentifiers = true
+
+	u := &User{}
+	rf.AddTableAlias(u, "(SELECT 1)")
+
+	assertStatement(t, "SELECT (SELECT 1).id FROM users", rf.Select(squirrel.Select(), &u.ID).From("users"))
+
+	rf = sqluct.Referencer{IdentifierQuoter: sqluct.QuoteANSI}
+
+	assert.NotPanics(t, func() { rf.AddTableAlias(&User{}, `u"; DROP TABLE users`) })
+}
+
+func TestReferencer_concurrent(t *testing.T) {
+	type Row struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	rf := sqluct.Referencer{IdentifierQuoter: sqluct.QuoteANSI}
+	row := &Row{}
+	rf.AddTableAlias(row, "r")
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			other := &Row{}
+			rf.AddTableAlias(other, "o")
+
+			for j := 0; j < 100; j++ {
+				assert.Equal(t, `"r"."id"`, rf.Ref(&row.ID))
+				assert.Equal(t, `"r"."name" = "o"."name"`, rf.Fmt("%s = %s", &row.Name, &other.Name))
+				assert.Equal(t, []string{`"r"."id"`, `"r"."name"`}, rf.Cols(row))
+				assert.Equal(t, "name", rf.Col(&row.Name))
+			}
+		}()
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,71 @@
+package sqluct
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// RowValue is a structure value bound as a row, see Row.
+type RowValue struct {
+	vals []interface{}
+}
+
+// Row makes a row value of structure fields in column order of mapper, e.g. to compare multiple columns at once.
+//
+// Row value can be used as an argument in squirrel expressions or as a value in Mapper.Where conditions,
+// it is rendered as `ROW(?,?,...)` with field values as bind arguments (Postgres, MySQL).
+//
+//	q.Where(squirrel.Expr("(user_id, role_id) = ?", sqluct.Row(key))) // (user_id, role_id) = ROW(?,?)
+//
+// When passed to database directly (e.g. as argument of a raw statement), Row is encoded as Postgres
+// composite literal `(v1,v2,...)`: NULL is an empty field, other values are double-quoted with `"` and `\`
+// escaped by backslash, so that it can be cast to a composite type.
+//
+//	st.ExecRaw(ctx, "INSERT INTO items (price) VALUES ($1::money_amount)", sqluct.Row(price))
+//
+// All fields are used regardless of omitempty, Columns option can be used to select fields.
+// Default mapper is used, see Mapper.Row and Storage.Row to map fields with custom mapper.
+func Row(structPtr interface{}, options ...func(*Options)) RowValue {
+	return mapper(nil).Row(structPtr, options...)
+}
+
+// Row makes a row value of structure fields in column order of mapper, see Row.
+func (sm *Mapper) Row(structPtr interface{}, options ...func(*Options)) RowValue {
+	o := Options{IgnoreOmitEmpty: true}
+
+	for _, option := range options {
+		option(&o)
+	}
+
+	_, vals := sm.columnsValues(reflect.Indirect(reflect.ValueOf(structPtr)), o)
+
+	return RowValue{vals: vals}
+}
+
+// ToSql renders ROW constructor with bind arguments.
+func (r RowValue) ToSql() (string, []interface{}, error) { //nolint // Method name matches ext. implementation.
+	return "ROW(" + strings.TrimSuffix(strings.Repeat("?,", len(r.vals)), ",") + ")", r.vals, nil
+}
+
+// Value encodes Postgres composite literal.
+func (r RowValue) Value() (driver.Value, error) {
+	res := strings.Builder{}
+
+	res.WriteString("(")
+
+	for i, v := range r.vals {
+		if i != 0 {
+			res.WriteString(",")
+		}
+
+		if err := writeArrayElem(&res, v, ""); err != nil {
+			return nil, fmt.Errorf("row field %d: %w", i, err)
+		}
+	}
+
+	res.WriteString(")")
+
+	return res.String(), nil
+}
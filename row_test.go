@@ -0,0 +1,68 @@
+package sqluct_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/bool64/sqluct"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRow(t *testing.T) {
+	type Key struct {
+		UserID int     `db:"user_id"`
+		Name   string  `db:"name,omitempty"`
+		Note   *string `db:"note"`
+		Active bool    `db:"active"`
+	}
+
+	k := Key{UserID: 1, Name: `a "b" \c`, Active: true}
+
+	v, err := sqluct.Row(k).Value()
+	require.NoError(t, err)
+	assert.Equal(t, `(1,"a \"b\" \\c",,true)`, v)
+
+	v, err = sqluct.Row(&Key{}).Value()
+	require.NoError(t, err)
+	assert.Equal(t, `(0,"",,false)`, v)
+
+	assertStatementArgs(t, "SELECT id FROM user_roles WHERE (user_id, name) = ROW($1,$2)", []interface{}{1, `a "b" \c`},
+		squirrel.Select("id").From("user_roles").
+			Where(squirrel.Expr("(user_id, name) = ?", sqluct.Row(k, sqluct.Columns("user_id", "name")))).
+			PlaceholderFormat(squirrel.Dollar))
+
+	type Filter struct {
+		Price sqluct.RowValue `db:"price"`
+	}
+
+	type Price struct {
+		Amount   int    `db:"amount"`
+		Currency string `db:"currency"`
+	}
+
+	sm := sqluct.Mapper{}
+
+	assertStatementArgs(t, "SELECT id FROM items WHERE (price = ROW(?,?))", []interface{}{100, "EUR"},
+		squirrel.Select("id").From("items").Where(sm.Where(Filter{Price: sqluct.Row(Price{Amount: 100, Currency: "EUR"})})))
+}
+
+func TestStorage_Row(t *testing.T) {
+	type Key struct {
+		UserID int
+		Name   string `db:"name"`
+		Skip   string `db:"-"`
+	}
+
+	k := Key{UserID: 1, Name: "John", Skip: "x"}
+
+	st := sqluct.NewStorage(nil)
+	st.Mapper = sqluct.NewMapperFunc("db", strings.ToLower)
+
+	assertStatementArgs(t, "(userid, name) = ROW(?,?)", []interface{}{1, "John"},
+		squirrel.Expr("(userid, name) = ?", st.Row(k)))
+
+	assertStatementArgs(t, "(name) = ROW(?)", []interface{}{"John"},
+		squirrel.Expr("(name) = ?", sqluct.Row(k)))
+}
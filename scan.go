@@ -0,0 +1,249 @@
+package sqluct
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/bool64/ctxd"
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// ScanMode defines how result columns are matched to fields of destination structure.
+type ScanMode int
+
+// Scan modes.
+const (
+	// ScanDefault fails if result has a column that is missing in destination structure.
+	ScanDefault ScanMode = iota
+
+	// ScanUnsafe ignores result columns that are missing in destination structure,
+	// it can be useful for evolving schemas.
+	ScanUnsafe
+
+	// ScanStrict additionally fails if destination structure has a field that is missing in result,
+	// it can be useful to catch typos in column names.
+	ScanStrict
+)
+
+// queryer returns transaction from context or database with respect to ScanMode.
+//
+// Replica is used if it is configured and context has no transaction and is not marked with ReadFromPrimary.
+func (s *Storage) queryer(ctx context.Context) sqlx.QueryerContext {
+	if tx := TxFromContext(ctx); tx != nil {
+		if s.ScanMode == ScanUnsafe {
+			return tx.Unsafe()
+		}
+
+		return tx
+	}
+
+	db := s.db
+	if s.Replica != nil && !readsFromPrimary(ctx) {
+		db = s.Replica
+	}
+
+	if s.ScanMode == ScanUnsafe {
+		return db.Unsafe()
+	}
+
+	return db
+}
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+	errInvalidDestination = errors.New("destination must be a non-nil pointer")
+	errMissingDestination = errors.New("missing destination field for column")
+)
+
+// selectStrict scans query result into destination checking that all fields of destination structure are populated.
+func (s *Storage) selectStrict(ctx context.Context, queryer sqlx.QueryerContext, dest interface{}, query string, args []interface{}) error {
+	rows, err := queryer.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close() //nolint:errcheck // Close error is not actionable.
+
+	t := reflect.TypeOf(dest).Elem()
+	isSlice := t.Kind() == reflect.Slice
+
+	if isSlice {
+		t = t.Elem()
+	}
+
+	t = reflectx.Deref(t)
+	isStruct := t.Kind() == reflect.Struct && !reflect.PtrTo(t).Implements(scannerType)
+
+	if isStruct {
+		columns, err := rows.Columns()
+		if err != nil {
+			return err
+		}
+
+		if missing := missingColumns(s.db.Mapper.TypeMap(t), columns); len(missing) > 0 {
+			return ctxd.NewError(ctx, "missing result columns", "columns", missing)
+		}
+	}
+
+	if isSlice {
+		return sqlx.StructScan(rows, dest)
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		return sql.ErrNoRows
+	}
+
+	if isStruct {
+		err = rows.StructScan(dest)
+	} else {
+		err = rows.Scan(dest)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return rows.Close()
+}
+
+// scanResultSet scans rows of current result set into destination without closing rows.
+//
+// Destination follows same rules as in Select: a slice receives all rows, other types receive
+// the first row or sql.ErrNoRows.
+func scanResultSet(rows *sqlx.Rows, dest interface{}, unsafe bool) error {
+	t := reflect.TypeOf(dest)
+	if t == nil || t.Kind() != reflect.Ptr || reflect.ValueOf(dest).IsNil() {
+		return errInvalidDestination
+	}
+
+	t = t.Elem()
+	isSlice := t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8
+
+	if !isSlice {
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+
+			return sql.ErrNoRows
+		}
+
+		if err := scanRow(rows, reflect.ValueOf(dest).Elem(), unsafe); err != nil {
+			return err
+		}
+
+		// Remaining rows of result set are skipped.
+		for rows.Next() {
+		}
+
+		return rows.Err()
+	}
+
+	sl := reflect.ValueOf(dest).Elem()
+	isPtr := t.Elem().Kind() == reflect.Ptr
+	base := reflectx.Deref(t.Elem())
+
+	sl.SetLen(0)
+
+	for rows.Next() {
+		v := reflect.New(base)
+
+		if err := scanRow(rows, v.Elem(), unsafe); err != nil {
+			return err
+		}
+
+		if isPtr {
+			sl.Set(reflect.Append(sl, v))
+		} else {
+			sl.Set(reflect.Append(sl, v.Elem()))
+		}
+	}
+
+	return rows.Err()
+}
+
+// scanRow scans current row into addressable value, columns are mapped to fields of structure with rows mapper.
+func scanRow(rows *sqlx.Rows, v reflect.Value, unsafe bool) error {
+	t := v.Type()
+
+	if reflect.PtrTo(t).Implements(scannerType) || t.Kind() != reflect.Struct || len(rows.Mapper.TypeMap(t).Index) == 0 {
+		return rows.Scan(v.Addr().Interface())
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	values := make([]interface{}, len(columns))
+
+	for i, traversal := range rows.Mapper.TraversalsByName(t, columns) {
+		if len(traversal) == 0 {
+			if !unsafe {
+				return fmt.Errorf("%w %q in %s", errMissingDestination, columns[i], t)
+			}
+
+			values[i] = new(interface{})
+
+			continue
+		}
+
+		values[i] = reflectx.FieldByIndexes(v, traversal).Addr().Interface()
+	}
+
+	return rows.Scan(values...)
+}
+
+// missingColumns returns names of structure fields that are not present in columns.
+func missingColumns(tm *reflectx.StructMap, columns []string) []string {
+	present := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		present[c] = true
+	}
+
+	var missing []string
+
+	for _, fi := range tm.Index {
+		if fi.Embedded || present[fi.Path] || !isLeafField(fi) {
+			continue
+		}
+
+		missing = append(missing, fi.Path)
+	}
+
+	return missing
+}
+
+// isLeafField checks if field is scanned as a single column.
+func isLeafField(fi *reflectx.FieldInfo) bool {
+	for p := fi.Parent; p != nil && p.Field.Type != nil; p = p.Parent {
+		if isScannerField(p) {
+			return false
+		}
+	}
+
+	if isScannerField(fi) {
+		return true
+	}
+
+	for _, c := range fi.Children {
+		if c != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isScannerField(fi *reflectx.FieldInfo) bool {
+	return reflect.PtrTo(reflectx.Deref(fi.Field.Type)).Implements(scannerType)
+}
@@ -0,0 +1,104 @@
+package sqluct
+
+import "strings"
+
+// SplitStatements splits SQL script into separate statements by semicolons.
+//
+// Semicolons in quoted strings and identifiers, comments and Postgres dollar-quoted strings are ignored.
+// Quotes are escaped by doubling as in standard SQL, backslash escapes are not recognized.
+// Resulting statements are trimmed, do not have trailing semicolons, empty and comment-only statements are omitted.
+func SplitStatements(script string) []string {
+	var (
+		res     []string
+		start   int
+		hasCode bool
+	)
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(script, i, c)
+		case strings.HasPrefix(script[i:], "--"):
+			i = skipUntil(script, i, "\n") - 1
+
+			continue
+		case strings.HasPrefix(script[i:], "/*"):
+			i = skipUntil(script, i+2, "*/") - 1
+
+			continue
+		case c == '$':
+			if tag := dollarTag(script[i:]); tag != "" {
+				i = skipUntil(script, i+len(tag), tag) - 1
+			}
+		case c == ';':
+			if hasCode {
+				res = append(res, strings.TrimSpace(script[start:i]))
+			}
+
+			start = i + 1
+			hasCode = false
+
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			continue
+		}
+
+		hasCode = true
+	}
+
+	if hasCode {
+		res = append(res, strings.TrimSpace(script[start:]))
+	}
+
+	return res
+}
+
+// skipQuoted returns position of closing quote or end of script.
+func skipQuoted(script string, pos int, quote byte) int {
+	for i := pos + 1; i < len(script); i++ {
+		if script[i] != quote {
+			continue
+		}
+
+		// Doubled quote is an escaped quote.
+		if i+1 < len(script) && script[i+1] == quote {
+			i++
+
+			continue
+		}
+
+		return i
+	}
+
+	return len(script) - 1
+}
+
+// skipUntil returns position after the end of closing sequence or end of script.
+func skipUntil(script string, pos int, end string) int {
+	i := strings.Index(script[pos:], end)
+	if i == -1 {
+		return len(script)
+	}
+
+	return pos + i + len(end)
+}
+
+// dollarTag returns opening tag of dollar-quoted string, e.g. $$ or $body$.
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		case c >= '0' && c <= '9' && i > 1:
+		default:
+			return ""
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,33 @@
+package sqluct_test
+
+import (
+	"testing"
+
+	"github.com/bool64/sqluct"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	assert.Equal(t, []string{
+		"CREATE TABLE foo (id INT, name TEXT DEFAULT 'a;b')",
+		"INSERT INTO foo VALUES (1, 'it''s; fine')",
+		`CREATE TABLE "semi;colon" (` + "`x;y`" + ` INT)`,
+		"-- comment;\nSELECT 1 /* inline; comment */ FROM foo",
+		"CREATE FUNCTION f() RETURNS INT AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql",
+		"SELECT $$a;b$$, $1",
+	}, sqluct.SplitStatements(`
+CREATE TABLE foo (id INT, name TEXT DEFAULT 'a;b');
+INSERT INTO foo VALUES (1, 'it''s; fine');;
+CREATE TABLE "semi;colon" (`+"`x;y`"+` INT);
+-- comment;
+SELECT 1 /* inline; comment */ FROM foo;
+CREATE FUNCTION f() RETURNS INT AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql;
+SELECT $$a;b$$, $1;
+-- trailing comment
+/* another; comment */
+`))
+
+	assert.Empty(t, sqluct.SplitStatements(" ; -- nothing\n"))
+	assert.Equal(t, []string{"SELECT 'unterminated;"}, sqluct.SplitStatements("SELECT 'unterminated;"))
+	assert.Equal(t, []string{"SELECT 1 /* unterminated;"}, sqluct.SplitStatements("SELECT 1 /* unterminated;"))
+}
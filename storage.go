@@ -3,8 +3,15 @@ package sqluct
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/bool64/ctxd"
@@ -36,6 +43,15 @@ func (s stmt) ToSql() (string, []interface{}, error) { //nolint // Method name m
 	return s.query, s.args, nil
 }
 
+// errStmt is a statement that fails to build.
+type errStmt struct {
+	err error
+}
+
+func (s errStmt) ToSql() (string, []interface{}, error) { //nolint // Method name matches ext. implementation.
+	return "", nil, s.err
+}
+
 // Stmt is a statement with placeholder arguments.
 func Stmt(query string, args ...interface{}) ToSQL {
 	return stmt{
@@ -44,6 +60,85 @@ func Stmt(query string, args ...interface{}) ToSQL {
 	}
 }
 
+// StmtStruct is a statement with placeholder arguments taken from struct fields.
+//
+// If query has named placeholders (e.g. `:user_id`), they are replaced with `?` and bound to values of
+// fields with matching column names, a name can be used multiple times, unknown name fails the statement.
+// Double colon (e.g. Postgres cast `::int`) and quoted strings are not treated as named placeholders.
+//
+// Otherwise, values of all fields are positional arguments in column order of Mapper, same as in InsertStmt:
+// fields are in declaration order, fields of embedded structures follow fields of outer structure.
+// Fields with `omitempty` are used regardless of value.
+//
+//	sqluct.StmtStruct("SELECT id FROM users WHERE role = :role AND name LIKE :name", filter)
+func StmtStruct(query string, argStruct interface{}) ToSQL {
+	cols, vals := mapper(nil).columnsValues(reflect.Indirect(reflect.ValueOf(argStruct)), Options{IgnoreOmitEmpty: true})
+
+	var (
+		res   strings.Builder
+		args  []interface{}
+		named bool
+	)
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		switch {
+		case c == '\'' || c == '"':
+			end := skipQuoted(query, i, c)
+			res.WriteString(query[i : end+1])
+			i = end
+
+			continue
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			res.WriteString("::")
+			i++
+
+			continue
+		case c == ':' && i+1 < len(query) && isIdentByte(query[i+1]):
+			j := i + 1
+			for j < len(query) && isIdentByte(query[j]) {
+				j++
+			}
+
+			name := query[i+1 : j]
+			found := false
+
+			for k, col := range cols {
+				if col == name {
+					args = append(args, vals[k])
+					found = true
+
+					break
+				}
+			}
+
+			if !found {
+				return errStmt{err: fmt.Errorf("%w %q in statement", errUnknownColumn, name)}
+			}
+
+			res.WriteByte('?')
+
+			named = true
+			i = j - 1
+
+			continue
+		}
+
+		res.WriteByte(c)
+	}
+
+	if !named {
+		return stmt{query: query, args: vals}
+	}
+
+	return stmt{query: res.String(), args: args}
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
 // Open opens a database specified by its database driver name and a
 // driver-specific data source name, usually consisting of at least a
 // database name and connection information.
@@ -56,8 +151,9 @@ func Open(driverName, dataSourceName string) (*Storage, error) {
 	dbx := sqlx.NewDb(db, driverName)
 
 	s := Storage{
-		db:     dbx,
-		Mapper: &Mapper{},
+		db:            dbx,
+		selectColumns: &selectColumnsCache{},
+		Mapper:        &Mapper{},
 	}
 
 	switch driverName {
@@ -81,14 +177,38 @@ func Open(driverName, dataSourceName string) (*Storage, error) {
 // NewStorage creates an instance of Storage.
 func NewStorage(db *sqlx.DB) *Storage {
 	return &Storage{
-		db: db,
+		db:            db,
+		selectColumns: &selectColumnsCache{},
 	}
 }
 
+// NewStorageFromDB creates an instance of Storage with database/sql instance.
+//
+// Driver name is used by sqlx to determine bind type, e.g. "postgres", "mysql" or "sqlite3".
+// Unlike Open, it does not configure Mapper, Format and IdentifierQuoter for the driver.
+func NewStorageFromDB(db *sql.DB, driverName string) *Storage {
+	return NewStorage(sqlx.NewDb(db, driverName))
+}
+
+// NewStorageFromConnector creates an instance of Storage with a connection pool over driver.Connector.
+//
+// It allows building Storage over a wrapped or instrumented pool (e.g. tracing or metrics connector),
+// see NewStorageFromDB for driver name semantics.
+func NewStorageFromConnector(connector driver.Connector, driverName string) *Storage {
+	return NewStorageFromDB(sql.OpenDB(connector), driverName)
+}
+
 // Storage creates and executes database statements.
 type Storage struct {
+	// generation identifies settings of Storage in selectColumns cache, it is first for atomic alignment.
+	generation uint64
+
 	db *sqlx.DB
 
+	// selectColumns caches columns of SelectStmt, it is nil (no caching) if Storage is not created
+	// with a constructor. Cache is shared by copies of Storage, generation distinguishes their settings.
+	selectColumns *selectColumnsCache
+
 	Mapper *Mapper
 
 	// Format is a placeholder format, default squirrel.Dollar.
@@ -97,32 +217,96 @@ type Storage struct {
 
 	// IdentifierQuoter is formatter of column and table names.
 	// Default QuoteNoop.
+	//
+	// Table name of a statement is quoted as a single identifier (including dots, if any),
+	// generated columns are quoted without table name, unless QualifyColumns option is used.
 	IdentifierQuoter func(tableAndColumn ...string) string
 
+	// Replica is an optional read replica database.
+	//
+	// If set, Query and Select (and helpers based on them) use Replica when there is no transaction in context
+	// and context is not marked with ReadFromPrimary. Exec and transactions always use primary database.
+	// Writes made with Select (e.g. custom statements with RETURNING) need ReadFromPrimary context.
+	Replica *sqlx.DB
+
+	// BaseBuilder is an optional base of statement builders made by QueryBuilder, default squirrel.StatementBuilder.
+	// Placeholder format and runner are applied on top of it.
+	BaseBuilder squirrel.StatementBuilderType
+
+	// DefaultOptions are applied before call-site options in statements and conditions made by Storage,
+	// e.g. SelectStmt, InsertStmt, UpdateStmt, WhereEq.
+	// Call-site options are applied later, so they can override values set by defaults.
+	DefaultOptions []func(*Options)
+
+	// ScanMode defines how result columns are matched to fields of destination, default ScanDefault.
+	// ScanUnsafe is applied in Select and Query, ScanStrict is only applied in Select.
+	ScanMode ScanMode
+
+	// MultiStatements enables SelectMulti to send queries as a single semicolon-joined statement
+	// and scan multiple result sets for Postgres, MySQL and MariaDB dialects.
+	// Database driver must support multiple statements with bind arguments (e.g. go-sql-driver/mysql with
+	// multiStatements and interpolateParams), lib/pq and pgx only support multiple statements without arguments.
+	MultiStatements bool
+
 	// OnError is called when error is encountered, could be useful for logging.
 	OnError func(ctx context.Context, err error)
 
 	// Trace wraps a call to database.
 	// It takes statement as arguments and returns
 	// instrumented context with callback to call after db call is finished.
+	// Operation label can be retrieved from context with LabelFromContext.
 	Trace func(ctx context.Context, stmt string, args []interface{}) (newCtx context.Context, onFinish func(error))
+
+	// Logger receives statement, arguments, duration and error of finished database calls, it is called
+	// in addition to Trace. See SlowQueryLogger.
+	Logger Logger
+
+	// StatementErrors enables wrapping of execution errors of Exec, Query and Select with StatementError
+	// to have failed statement in error details.
+	StatementErrors bool
+
+	// StatementErrorArgs enables statement arguments in StatementError, RedactArgs is applied to them.
+	// Arguments are not added by default, as they may contain sensitive data.
+	StatementErrorArgs bool
+
+	// CommentFromContext returns an optional comment for a statement, e.g. in sqlcommenter format
+	// `application='x',controller='y'`. Comment is prepended to statements in Exec, Query and Select
+	// right before execution, so that it is also visible in Trace.
+	CommentFromContext func(ctx context.Context) string
+
+	// RedactArgs is applied to a copy of statement arguments before passing them to Trace,
+	// statement is executed with original arguments.
+	// See RedactArgsByIndex and RedactArgsByColumn.
+	RedactArgs func(stmt string, args []interface{}) []interface{}
 }
 
 // Dialect defines SQL dialect.
 type Dialect string
 
 // Supported dialects.
+//
+// DialectMariaDB is a variant of DialectMySQL with RETURNING clause (MariaDB 10.5+), it is not detected by Open
+// with "mysql" driver and should be set to Mapper explicitly.
 const (
 	DialectUnknown  = Dialect("")
 	DialectMySQL    = Dialect("mysql")
+	DialectMariaDB  = Dialect("mariadb")
 	DialectPostgres = Dialect("postgres")
 	DialectSQLite3  = Dialect("sqlite3")
 )
 
+// isMySQL checks if dialect belongs to MySQL family.
+func isMySQL(d Dialect) bool {
+	return d == DialectMySQL || d == DialectMariaDB
+}
+
 // InTx runs callback in a transaction.
 //
 // If transaction already exists, it will reuse that. Otherwise, it starts a new transaction and commit or rollback
 // (in case of error) at the end.
+//
+// Callbacks registered with OnCommit and OnRollback run after transaction is finished.
+// If callback panics, transaction is rolled back and panic is propagated.
 func (s *Storage) InTx(ctx context.Context, fn func(context.Context) error) (err error) {
 	var finish func(ctx context.Context, err error) error
 
@@ -136,6 +320,7 @@ func (s *Storage) InTx(ctx context.Context, fn func(context.Context) error) (err
 		}
 
 		ctx = TxToContext(ctx, tx)
+		ctx = context.WithValue(ctx, txHooksCtxKey{}, &txHooks{})
 	} else {
 		// Do nothing because parent tx is still running and
 		// this is not the beginner, so it can't be the finisher.
@@ -145,6 +330,13 @@ func (s *Storage) InTx(ctx context.Context, fn func(context.Context) error) (err
 	}
 
 	defer func() {
+		// Rolling back transaction of panicking callback.
+		if r := recover(); r != nil {
+			_ = finish(ctx, ctxd.NewError(ctx, "panic in transaction", "panic", r))
+
+			panic(r)
+		}
+
 		err = finish(ctx, err)
 	}()
 
@@ -160,6 +352,8 @@ func (s *Storage) submitTx(ctx context.Context, err error) error {
 	}
 
 	if err != nil {
+		defer s.runRollbackHooks(ctx)
+
 		if rbErr := tx.Rollback(); rbErr != nil {
 			return s.error(ctx, ctxd.WrapError(ctx, rbErr, "failed to rollback",
 				"error", err,
@@ -170,10 +364,59 @@ func (s *Storage) submitTx(ctx context.Context, err error) error {
 	}
 
 	if err := tx.Commit(); err != nil {
+		s.runRollbackHooks(ctx)
+
 		return s.error(ctx, ctxd.WrapError(ctx, err, "failed to commit"))
 	}
 
-	return nil
+	return s.runCommitHooks(ctx)
+}
+
+// hooksContext detaches finished transaction and its hooks from context.
+func hooksContext(ctx context.Context) (context.Context, *txHooks) {
+	h := txHooksFromContext(ctx)
+	if h == nil {
+		return ctx, nil
+	}
+
+	ctx = TxToContext(ctx, nil)
+	ctx = context.WithValue(ctx, txHooksCtxKey{}, (*txHooks)(nil))
+
+	return ctx, h
+}
+
+func (s *Storage) runCommitHooks(ctx context.Context) error {
+	ctx, h := hooksContext(ctx)
+	if h == nil {
+		return nil
+	}
+
+	var res error
+
+	for i, fn := range h.onCommit {
+		if err := fn(ctx); err != nil {
+			err = s.error(ctx, ctxd.WrapError(ctx, err, "commit hook #"+strconv.Itoa(i)+" failed"))
+
+			if res == nil {
+				res = err
+			}
+		}
+	}
+
+	return res
+}
+
+func (s *Storage) runRollbackHooks(ctx context.Context) {
+	ctx, h := hooksContext(ctx)
+	if h == nil {
+		return
+	}
+
+	for i, fn := range h.onRollback {
+		if err := fn(ctx); err != nil {
+			_ = s.error(ctx, ctxd.WrapError(ctx, err, "rollback hook #"+strconv.Itoa(i)+" failed"))
+		}
+	}
 }
 
 // Exec executes query according to query builder.
@@ -190,74 +433,283 @@ func (s *Storage) Exec(ctx context.Context, qb ToSQL) (res sql.Result, err error
 		return nil, s.error(ctx, ctxd.WrapError(ctx, err, "failed to build query"))
 	}
 
-	if s.Trace != nil {
-		ct, def := s.Trace(ctx, query, args)
-		ctx = ct
+	query = s.withComment(ctx, query)
 
-		defer func() { def(err) }()
-	}
+	ctx, finish := s.trace(ctx, query, args)
+	defer func() { finish(err) }()
 
 	res, err = execer.ExecContext(ctx, query, args...)
 	if err != nil {
+		err = s.stmtError(err, query, args)
+
 		return nil, s.error(ctx, err)
 	}
 
 	return res, nil
 }
 
+// StatementError is an error of statement execution, see Storage.StatementErrors.
+type StatementError struct {
+	Query string
+	Args  []interface{}
+	Err   error
+}
+
+// Error implements error.
+func (e StatementError) Error() string {
+	return e.Err.Error() + " (query: " + e.Query + ")"
+}
+
+// Unwrap returns execution error.
+func (e StatementError) Unwrap() error {
+	return e.Err
+}
+
+// stmtError wraps execution error with statement if enabled, sql.ErrNoRows is not wrapped.
+func (s *Storage) stmtError(err error, query string, args []interface{}) error {
+	if err == nil || !s.StatementErrors || errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	e := StatementError{Query: query, Err: err}
+
+	if s.StatementErrorArgs {
+		e.Args = s.traceArgs(query, args)
+	}
+
+	return e
+}
+
+// ErrUnexpectedRowsAffected is returned by ExecExpect when number of affected rows differs from expected.
+type ErrUnexpectedRowsAffected struct {
+	Got  int64
+	Want int64
+}
+
+// Error implements error.
+func (e ErrUnexpectedRowsAffected) Error() string {
+	return "unexpected rows affected: got " + strconv.FormatInt(e.Got, 10) + ", want " + strconv.FormatInt(e.Want, 10)
+}
+
+// NotFoundError is returned by StorageOf.Get when row is not found.
+//
+// It unwraps to sql.ErrNoRows, so errors.Is(err, sql.ErrNoRows) is true.
+type NotFoundError struct {
+	Table string
+}
+
+// Error implements error.
+func (e NotFoundError) Error() string {
+	return "row not found in table " + e.Table
+}
+
+// Unwrap returns sql.ErrNoRows.
+func (e NotFoundError) Unwrap() error {
+	return sql.ErrNoRows
+}
+
+// ExecRaw executes raw statement with arguments, it is a shortcut for Exec(ctx, Stmt(query, args...)).
+//
+// Placeholders are not reformatted, so statement must use placeholders supported by database.
+//
+//	_, err := st.ExecRaw(ctx, "CREATE INDEX users_name ON users (name)")
+func (s *Storage) ExecRaw(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.Exec(ctx, Stmt(query, args...))
+}
+
+// ExecExpect executes query according to query builder and checks number of affected rows.
+//
+// It returns ErrUnexpectedRowsAffected if number of affected rows is different from wantAffected,
+// for example to detect lost optimistic update of a single row.
+// Please note, statement is not rolled back in case of unexpected number of rows, use InTx if necessary.
+func (s *Storage) ExecExpect(ctx context.Context, qb ToSQL, wantAffected int64) error {
+	res, err := s.Exec(ctx, qb)
+	if err != nil {
+		return err
+	}
+
+	got, err := res.RowsAffected()
+	if err != nil {
+		return s.error(ctx, ctxd.WrapError(ctx, err, "failed to get rows affected"))
+	}
+
+	if got != wantAffected {
+		return s.error(ctx, ErrUnexpectedRowsAffected{Got: got, Want: wantAffected})
+	}
+
+	return nil
+}
+
+// ExecMany splits SQL script into statements with SplitStatements and executes them one by one in a transaction.
+//
+// Execution stops on first failed statement, index and text of that statement are added to the error.
+// Please note, some databases (e.g. MySQL) implicitly commit transaction on schema changes.
+func (s *Storage) ExecMany(ctx context.Context, script string) error {
+	return s.InTx(ctx, func(ctx context.Context) error {
+		for i, st := range SplitStatements(script) {
+			if _, err := s.Exec(ctx, StringStatement(st)); err != nil {
+				return ctxd.WrapError(ctx, err, "failed to execute statement #"+strconv.Itoa(i),
+					"statement", st,
+				)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Rows is a raw result of QueryRows.
+//
+// Trace and Logger of Storage are finished when rows are closed, so that duration includes iteration.
+type Rows struct {
+	*sqlx.Rows
+
+	finish func(error)
+	once   sync.Once
+}
+
+// Close closes rows and finishes tracing with iteration error if any.
+func (r *Rows) Close() error {
+	iterErr := r.Rows.Err()
+	err := r.Rows.Close()
+
+	r.once.Do(func() {
+		if iterErr != nil {
+			r.finish(iterErr)
+		} else {
+			r.finish(err)
+		}
+	})
+
+	return err
+}
+
 // Query queries database and returns raw result.
 //
 // You must close the rows after use to avoid resource leak.
 // Select is recommended to use instead of Query.
 func (s *Storage) Query(ctx context.Context, qb ToSQL) (*sqlx.Rows, error) {
+	rows, finish, err := s.query(ctx, qb)
+	if err != nil {
+		return nil, err
+	}
+
+	finish(nil)
+
+	return rows, nil
+}
+
+// QueryRows queries database and returns raw result, tracing is finished when rows are closed.
+//
+// Unlike Query, duration reported to Trace and Logger includes iteration over rows.
+// You must close the rows after use to avoid resource leak.
+func (s *Storage) QueryRows(ctx context.Context, qb ToSQL) (*Rows, error) {
+	rows, finish, err := s.query(ctx, qb)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Rows{Rows: rows, finish: finish}, nil
+}
+
+func (s *Storage) query(ctx context.Context, qb ToSQL) (*sqlx.Rows, func(error), error) {
 	query, args, err := qb.ToSql()
 	if err != nil {
-		return nil, s.error(ctx, ctxd.WrapError(ctx, err, "failed to build query"))
+		return nil, nil, s.error(ctx, ctxd.WrapError(ctx, err, "failed to build query"))
 	}
 
-	if s.Trace != nil {
-		ct, def := s.Trace(ctx, query, args)
-		ctx = ct
+	query = s.withComment(ctx, query)
+
+	ctx, finish := s.trace(ctx, query, args)
+
+	rows, err := s.queryer(ctx).QueryxContext(ctx, query, args...) //nolint:sqlclosecheck // Caller closes rows.
+	if err != nil {
+		err = s.stmtError(err, query, args)
+		finish(err)
 
-		defer func() { def(err) }()
+		return nil, nil, s.error(ctx, err)
 	}
 
-	var queryer sqlx.QueryerContext
-	if tx := TxFromContext(ctx); tx != nil {
-		queryer = tx
-	} else {
-		queryer = s.db
+	return rows, finish, nil
+}
+
+// QueryMaps queries database and returns rows as maps of column names to values.
+//
+// It can be used for schema-agnostic access when columns are not known in advance.
+// Values of []byte type are converted to string.
+func (s *Storage) QueryMaps(ctx context.Context, qb ToSQL) ([]map[string]interface{}, error) {
+	var res []map[string]interface{}
+
+	err := s.QueryMapsFunc(ctx, qb, func(row map[string]interface{}) error {
+		res = append(res, row)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	rows, err := queryer.QueryxContext(ctx, query, args...) //nolint:sqlclosecheck // Caller closes rows.
+	return res, nil
+}
+
+// QueryMapsFunc queries database and calls fn for each row as a map of column names to values.
+//
+// Iteration stops on first error returned by fn.
+// Values of []byte type are converted to string.
+func (s *Storage) QueryMapsFunc(ctx context.Context, qb ToSQL, fn func(row map[string]interface{}) error) (err error) {
+	rows, err := s.Query(ctx, qb)
 	if err != nil {
-		return nil, s.error(ctx, err)
+		return err
 	}
 
-	return rows, nil
+	defer func() {
+		if clErr := rows.Close(); clErr != nil && err == nil {
+			err = s.error(ctx, clErr)
+		}
+	}()
+
+	for rows.Next() {
+		row := make(map[string]interface{})
+
+		if err := rows.MapScan(row); err != nil {
+			return s.error(ctx, err)
+		}
+
+		for k, v := range row {
+			if b, ok := v.([]byte); ok {
+				row[k] = string(b)
+			}
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	return s.error(ctx, rows.Err())
 }
 
 // Select queries statement of query builder and scans result into destination.
 //
-// Destination can be a pointer to struct or slice, e.g. `*row` or `*[]row`.
+// Destination can be a pointer to struct or slice, e.g. `*row`, `*[]row` or `*[]*row`,
+// slice of pointers avoids copying of large structures, every row is allocated separately.
 func (s *Storage) Select(ctx context.Context, qb ToSQL, dest interface{}) (err error) {
 	query, args, err := qb.ToSql()
 	if err != nil {
 		return s.error(ctx, ctxd.WrapError(ctx, err, "failed to build query"))
 	}
 
-	if s.Trace != nil {
-		ct, def := s.Trace(ctx, query, args)
-		ctx = ct
+	query = s.withComment(ctx, query)
 
-		defer func() { def(err) }()
-	}
+	ctx, finish := s.trace(ctx, query, args)
+	defer func() { finish(err) }()
 
-	var queryer sqlx.QueryerContext
-	if tx := TxFromContext(ctx); tx != nil {
-		queryer = tx
-	} else {
-		queryer = s.db
+	queryer := s.queryer(ctx)
+
+	if s.ScanMode == ScanStrict {
+		err = s.stmtError(s.selectStrict(ctx, queryer, dest, query, args), query, args)
+
+		return s.error(ctx, err)
 	}
 
 	kind := reflect.Indirect(reflect.ValueOf(dest)).Kind()
@@ -267,10 +719,337 @@ func (s *Storage) Select(ctx context.Context, qb ToSQL, dest interface{}) (err e
 		err = sqlx.GetContext(ctx, queryer, dest, query, args...)
 	}
 
+	err = s.stmtError(err, query, args)
+
 	return s.error(ctx, err)
 }
 
+// Count returns number of rows matching select query.
+//
+// Columns, limit and offset of query builder are replaced, and it is counted as a subquery,
+// e.g. SELECT COUNT(*) FROM (SELECT 1 FROM orders WHERE ... ORDER BY ...) AS t,
+// so that ORDER BY, GROUP BY and locking clauses of query are preserved.
+func (s *Storage) Count(ctx context.Context, qb squirrel.SelectBuilder) (int64, error) {
+	return s.count(ctx, qb, "")
+}
+
+// CountDistinct returns number of distinct non-NULL values of field in rows matching select query.
+//
+// Field pointer is resolved with Referencer (Storage.MakeReferencer is used if rf is nil),
+// Quoted value (e.g. st.Q("orders", "user_id")) can be used instead of field pointer.
+// Query is counted as a subquery same way as in Count.
+//
+//	cnt, err := st.CountDistinct(ctx, rf.From(st.QueryBuilder().Select(), order, "orders", ""), rf, &order.UserID)
+//
+// Error is returned if field pointer is unknown.
+func (s *Storage) CountDistinct(
+	ctx context.Context,
+	qb squirrel.SelectBuilder,
+	rf *Referencer,
+	fieldPtr interface{},
+) (int64, error) {
+	if rf == nil {
+		rf = s.MakeReferencer()
+	}
+
+	column, err := rf.ref(fieldPtr)
+	if err != nil {
+		return 0, s.error(ctx, ctxd.WrapError(ctx, err, "failed to resolve distinct column"))
+	}
+
+	return s.count(ctx, qb, column)
+}
+
+func (s *Storage) count(ctx context.Context, qb squirrel.SelectBuilder, column string) (int64, error) {
+	var cnt int64
+
+	expr, col := "COUNT(*)", "1"
+	if column != "" {
+		expr, col = "COUNT(DISTINCT t.c)", column+" AS c"
+	}
+
+	q := s.QueryBuilder().Select(expr).
+		FromSelect(qb.RemoveColumns().RemoveLimit().RemoveOffset().Columns(col), "t")
+
+	err := s.Select(ctx, q, &cnt)
+
+	return cnt, err
+}
+
+// SelectRaw queries raw statement with arguments and scans result into destination,
+// it is a shortcut for Select(ctx, Stmt(query, args...), dest).
+//
+// Placeholders are not reformatted, so statement must use placeholders supported by database.
+//
+//	err := st.SelectRaw(ctx, &ids, "SELECT id FROM users WHERE name = $1", name)
+func (s *Storage) SelectRaw(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return s.Select(ctx, Stmt(query, args...), dest)
+}
+
+// SelectJoined queries statement with aliased columns of row structures and scans result into destination.
+//
+// It is a shortcut for Select with rf.SelectJoined, see Referencer.SelectJoined for details.
+//
+//	err := st.SelectJoined(ctx, rf, q.From(rf.Fmt("%s AS %s", rf.Q("users"), user)).Join(...), &rows, user, role)
+func (s *Storage) SelectJoined(
+	ctx context.Context,
+	rf *Referencer,
+	qb squirrel.SelectBuilder,
+	dest interface{},
+	rowStructPtrs ...interface{},
+) error {
+	return s.Select(ctx, rf.SelectJoined(qb, rowStructPtrs...), dest)
+}
+
+// SelectMulti queries statements of query builders and scans results into respective destinations.
+//
+// By default, queries are executed sequentially in a single transaction (existing transaction is reused) to have
+// a consistent view of data, destinations follow same rules as in Select.
+// Execution stops on first failed query, index of that query is added to the error.
+//
+// If MultiStatements is enabled for a supported dialect and ScanMode is not ScanStrict, queries are sent
+// in a single round trip as one statement with multiple result sets, `$N` placeholders are renumbered.
+func (s *Storage) SelectMulti(ctx context.Context, queries []ToSQL, dests []interface{}) error {
+	if len(queries) != len(dests) {
+		return s.error(ctx, ctxd.NewError(ctx, "number of queries and destinations mismatch",
+			"queries", len(queries), "destinations", len(dests)))
+	}
+
+	if d := mapper(s.Mapper).Dialect; s.MultiStatements && len(queries) > 1 && s.ScanMode != ScanStrict &&
+		(d == DialectPostgres || isMySQL(d)) {
+		return s.selectBatch(ctx, queries, dests)
+	}
+
+	return s.InTx(ctx, func(ctx context.Context) error {
+		for i, qb := range queries {
+			if err := s.Select(ctx, qb, dests[i]); err != nil {
+				return ctxd.WrapError(ctx, err, "failed to select query #"+strconv.Itoa(i))
+			}
+		}
+
+		return nil
+	})
+}
+
+// selectBatch queries statements as a single multi-statement query and scans result sets into destinations.
+func (s *Storage) selectBatch(ctx context.Context, queries []ToSQL, dests []interface{}) (err error) {
+	var (
+		stmts = make([]string, 0, len(queries))
+		args  []interface{}
+	)
+
+	for i, qb := range queries {
+		query, qArgs, err := qb.ToSql()
+		if err != nil {
+			return s.error(ctx, ctxd.WrapError(ctx, err, "failed to build query #"+strconv.Itoa(i)))
+		}
+
+		stmts = append(stmts, shiftPlaceholders(query, len(args)))
+		args = append(args, qArgs...)
+	}
+
+	query := s.withComment(ctx, strings.Join(stmts, "; "))
+
+	ctx, finish := s.trace(ctx, query, args)
+	defer func() { finish(err) }()
+
+	rows, err := s.queryer(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		return s.error(ctx, s.stmtError(err, query, args))
+	}
+
+	defer rows.Close() //nolint:errcheck // Close error is checked explicitly after scanning.
+
+	for i, dest := range dests {
+		if i > 0 && !rows.NextResultSet() {
+			err = rows.Err()
+			if err == nil {
+				err = ctxd.NewError(ctx, "missing result set")
+			}
+		} else {
+			err = scanResultSet(rows, dest, s.ScanMode == ScanUnsafe)
+		}
+
+		if err != nil {
+			err = ctxd.WrapError(ctx, err, "failed to select query #"+strconv.Itoa(i))
+
+			return s.error(ctx, s.stmtError(err, query, args))
+		}
+	}
+
+	return s.error(ctx, s.stmtError(rows.Close(), query, args))
+}
+
+// shiftPlaceholders increments numbers of `$N` placeholders by offset.
+func shiftPlaceholders(query string, offset int) string {
+	if offset == 0 || !strings.Contains(query, "$") {
+		return query
+	}
+
+	placeholders := findPlaceholders(query)
+	res := strings.Builder{}
+
+	for pos := 0; pos < len(query); pos++ {
+		i, ok := placeholders[pos]
+		if !ok || query[pos] != '$' {
+			res.WriteByte(query[pos])
+
+			continue
+		}
+
+		res.WriteString("$" + strconv.Itoa(i+1+offset))
+
+		for pos+1 < len(query) && query[pos+1] >= '0' && query[pos+1] <= '9' {
+			pos++
+		}
+	}
+
+	return res.String()
+}
+
+// Explain returns execution plan of a statement as text, rows of plan are joined with new lines.
+//
+// EXPLAIN (ANALYZE, FORMAT TEXT) is used for Postgres with analyze, EXPLAIN FORMAT=TREE or EXPLAIN ANALYZE for MySQL,
+// EXPLAIN or ANALYZE for MariaDB, EXPLAIN QUERY PLAN for SQLite (analyze is not supported and ignored).
+// Please note, statement is actually executed with analyze, use a transaction to roll back changes.
+// Primary database is used even if Storage.Replica is configured.
+func (s *Storage) Explain(ctx context.Context, qb ToSQL, analyze bool) (string, error) {
+	var prefix string
+
+	switch d := mapper(s.Mapper).Dialect; d {
+	case DialectPostgres:
+		prefix = "EXPLAIN "
+		if analyze {
+			prefix = "EXPLAIN (ANALYZE, FORMAT TEXT) "
+		}
+	case DialectMySQL:
+		prefix = "EXPLAIN FORMAT=TREE "
+		if analyze {
+			prefix = "EXPLAIN ANALYZE "
+		}
+	case DialectMariaDB:
+		prefix = "EXPLAIN "
+		if analyze {
+			prefix = "ANALYZE "
+		}
+	case DialectSQLite3:
+		prefix = "EXPLAIN QUERY PLAN "
+	default:
+		return "", s.error(ctx, ctxd.NewError(ctx, "explain is not supported for dialect", "dialect", d))
+	}
+
+	query, args, err := qb.ToSql()
+	if err != nil {
+		return "", s.error(ctx, ctxd.WrapError(ctx, err, "failed to build query"))
+	}
+
+	rows, err := s.Query(ReadFromPrimary(ctx), Stmt(prefix+query, args...))
+	if err != nil {
+		return "", err
+	}
+
+	defer rows.Close() //nolint:errcheck // Close error is not actionable.
+
+	var plan []string
+
+	for rows.Next() {
+		row, err := rows.SliceScan()
+		if err != nil {
+			return "", s.error(ctx, err)
+		}
+
+		// Plan text is in the last column (detail column of SQLite).
+		switch v := row[len(row)-1].(type) {
+		case []byte:
+			plan = append(plan, string(v))
+		default:
+			plan = append(plan, fmt.Sprint(v))
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return "", s.error(ctx, err)
+	}
+
+	return strings.Join(plan, "\n"), nil
+}
+
+// TableExists checks if table exists in database schema.
+//
+// Empty schema stands for current schema in Postgres, current database in MySQL and "main" in SQLite.
+func (s *Storage) TableExists(ctx context.Context, schema, table string) (bool, error) {
+	qb := s.QueryBuilder().Select("COUNT(*)")
+
+	switch d := mapper(s.Mapper).Dialect; d {
+	case DialectPostgres, DialectMySQL, DialectMariaDB:
+		qb = qb.From("information_schema.tables").
+			Where(schemaEq(d, schema)).
+			Where(squirrel.Eq{"table_name": table})
+	case DialectSQLite3:
+		qb = qb.From(QuoteANSI(sqliteSchema(schema), "sqlite_master")).
+			Where(squirrel.Eq{"type": "table", "name": table})
+	default:
+		return false, s.error(ctx, ctxd.NewError(ctx, "introspection is not supported for dialect", "dialect", d))
+	}
+
+	return s.exists(ctx, qb)
+}
+
+// ColumnExists checks if column exists in a table of database schema.
+//
+// Empty schema stands for current schema in Postgres, current database in MySQL and "main" in SQLite.
+func (s *Storage) ColumnExists(ctx context.Context, schema, table, column string) (bool, error) {
+	qb := s.QueryBuilder().Select("COUNT(*)")
+
+	switch d := mapper(s.Mapper).Dialect; d {
+	case DialectPostgres, DialectMySQL, DialectMariaDB:
+		qb = qb.From("information_schema.columns").
+			Where(schemaEq(d, schema)).
+			Where(squirrel.Eq{"table_name": table, "column_name": column})
+	case DialectSQLite3:
+		// Hidden columns of table-valued pragma function are used to pass arguments.
+		qb = qb.From("pragma_table_info").
+			Where(squirrel.Eq{"arg": table, "schema": sqliteSchema(schema), "name": column})
+	default:
+		return false, s.error(ctx, ctxd.NewError(ctx, "introspection is not supported for dialect", "dialect", d))
+	}
+
+	return s.exists(ctx, qb)
+}
+
+func schemaEq(d Dialect, schema string) squirrel.Sqlizer {
+	if schema != "" {
+		return squirrel.Eq{"table_schema": schema}
+	}
+
+	if isMySQL(d) {
+		return squirrel.Expr("table_schema = DATABASE()")
+	}
+
+	return squirrel.Expr("table_schema = current_schema()")
+}
+
+func sqliteSchema(schema string) string {
+	if schema == "" {
+		return "main"
+	}
+
+	return schema
+}
+
+func (s *Storage) exists(ctx context.Context, qb ToSQL) (bool, error) {
+	var cnt int
+
+	if err := s.Select(ctx, qb, &cnt); err != nil {
+		return false, err
+	}
+
+	return cnt > 0, nil
+}
+
 // QueryBuilder returns query builder with placeholder format.
+//
+// Builders can be executed directly, e.g. with ExecContext, transaction from context is used if available.
 func (s *Storage) QueryBuilder() squirrel.StatementBuilderType {
 	format := s.Format
 
@@ -278,10 +1057,76 @@ func (s *Storage) QueryBuilder() squirrel.StatementBuilderType {
 		format = squirrel.Dollar
 	}
 
-	return squirrel.StatementBuilder.PlaceholderFormat(format).RunWith(s.db)
+	return s.QueryBuilderFormat(format)
+}
+
+// QueryBuilderFormat returns query builder with placeholder format that overrides Format, e.g. to render
+// a statement for embedding in another system (a function body with `$1` while application uses `?`).
+//
+// Builders made with SelectStmt, InsertStmt, UpdateStmt and DeleteStmt can also have placeholder format
+// overridden with their PlaceholderFormat method.
+//
+//	q := st.QueryBuilderFormat(squirrel.Dollar).Select("id").From("users").Where(squirrel.Eq{"name": "John"})
+func (s *Storage) QueryBuilderFormat(format squirrel.PlaceholderFormat) squirrel.StatementBuilderType {
+	base := s.BaseBuilder
+	if base == (squirrel.StatementBuilderType{}) {
+		base = squirrel.StatementBuilder
+	}
+
+	return base.PlaceholderFormat(format).RunWith(txRunner{s: s})
+}
+
+// txRunner is a squirrel runner that uses transaction from context if available.
+//
+// Builders made by Storage can be executed directly with ExecContext, QueryContext or QueryRowContext
+// and they would participate in transaction started with InTx.
+// Please note, Trace is not applied to such direct calls, Storage.Exec and Storage.Select are preferred.
+type txRunner struct {
+	s *Storage
+}
+
+func (r txRunner) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return r.s.db.Exec(query, args...)
+}
+
+func (r txRunner) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return r.s.db.Query(query, args...) //nolint:sqlclosecheck // Caller closes rows.
+}
+
+func (r txRunner) QueryRow(query string, args ...interface{}) squirrel.RowScanner {
+	return r.s.db.QueryRow(query, args...)
+}
+
+func (r txRunner) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if tx := TxFromContext(ctx); tx != nil {
+		return tx.ExecContext(ctx, query, args...)
+	}
+
+	return r.s.db.ExecContext(ctx, query, args...)
+}
+
+func (r txRunner) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if tx := TxFromContext(ctx); tx != nil {
+		return tx.QueryContext(ctx, query, args...) //nolint:sqlclosecheck // Caller closes rows.
+	}
+
+	return r.s.db.QueryContext(ctx, query, args...) //nolint:sqlclosecheck // Caller closes rows.
+}
+
+func (r txRunner) QueryRowContext(ctx context.Context, query string, args ...interface{}) squirrel.RowScanner {
+	if tx := TxFromContext(ctx); tx != nil {
+		return tx.QueryRowContext(ctx, query, args...)
+	}
+
+	return r.s.db.QueryRowContext(ctx, query, args...)
 }
 
 func (s *Storage) options(options []func(*Options)) []func(*Options) {
+	if len(s.DefaultOptions) > 0 {
+		options = append(append(make([]func(*Options), 0, len(s.DefaultOptions)+len(options)+1),
+			s.DefaultOptions...), options...)
+	}
+
 	if s.IdentifierQuoter != nil {
 		options = append(options, func(options *Options) {
 			if options.PrepareColumn == nil {
@@ -295,7 +1140,22 @@ func (s *Storage) options(options []func(*Options)) []func(*Options) {
 	return options
 }
 
+// Union combines SELECT statements with UNION (or UNION ALL if all is true) using placeholder format of Storage.
+//
+// See UnionStmt for details.
+func (s *Storage) Union(all bool, builders ...squirrel.SelectBuilder) UnionStmt {
+	format := s.Format
+
+	if format == nil {
+		format = squirrel.Dollar
+	}
+
+	return Union(all, builders...).PlaceholderFormat(format)
+}
+
 // SelectStmt makes a select query builder.
+//
+// Columns are not prefixed with table name, use QualifyColumns or Referencer.ColumnsOf options to prefix them.
 func (s *Storage) SelectStmt(tableName string, columns interface{}, options ...func(*Options)) squirrel.SelectBuilder {
 	if s.IdentifierQuoter != nil {
 		tableName = s.IdentifierQuoter(tableName)
@@ -303,7 +1163,121 @@ func (s *Storage) SelectStmt(tableName string, columns interface{}, options ...f
 
 	qb := s.QueryBuilder().Select().From(tableName)
 
-	return mapper(s.Mapper).Select(qb, columns, s.options(options)...).RunWith(s.db)
+	// Columns of a type are cached if there are no options that could affect them.
+	if s.selectColumns != nil && columns != nil && len(options) == 0 && len(s.DefaultOptions) == 0 {
+		k := selectColumnsKey{
+			typ:        reflect.TypeOf(columns),
+			mapper:     s.Mapper,
+			generation: atomic.LoadUint64(&s.generation),
+		}
+
+		if cols, ok := s.selectColumns.load(k); ok {
+			return qb.Columns(cols...)
+		}
+
+		o := Options{}
+
+		for _, option := range s.options(nil) {
+			option(&o)
+		}
+
+		sm := mapper(s.Mapper)
+		cols := sm.selectColumns(columns, o)
+		s.selectColumns.store(k, cols, sm.MaxCachedTypes)
+
+		return qb.Columns(cols...)
+	}
+
+	qualify := func(o *Options) {
+		if o.QualifyColumns && o.PrepareColumn == nil {
+			o.PrepareColumn = func(col string) string {
+				if s.IdentifierQuoter != nil {
+					col = s.IdentifierQuoter(col)
+				}
+
+				return tableName + "." + col
+			}
+		}
+	}
+
+	return mapper(s.Mapper).Select(qb, columns, s.options(append(options[:len(options):len(options)], qualify))...)
+}
+
+// generations provides unique generations of Storage settings.
+var generations uint64
+
+// selectColumnsKey identifies cached columns of SelectStmt.
+type selectColumnsKey struct {
+	typ        reflect.Type
+	mapper     *Mapper
+	generation uint64
+}
+
+// selectColumnsCache caches columns of SelectStmt.
+type selectColumnsCache struct {
+	mu   sync.RWMutex
+	cols map[selectColumnsKey][]string
+}
+
+func (c *selectColumnsCache) load(k selectColumnsKey) ([]string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	cols, ok := c.cols[k]
+
+	return cols, ok
+}
+
+// store adds columns to cache, cache is cleared when maxItems limit (if positive) is reached.
+func (c *selectColumnsCache) store(k selectColumnsKey, cols []string, maxItems int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cols == nil || (maxItems > 0 && len(c.cols) >= maxItems) {
+		c.cols = make(map[selectColumnsKey][]string, 1)
+	}
+
+	c.cols[k] = cols
+}
+
+func (c *selectColumnsCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cols = nil
+}
+
+// SetQuoter sets IdentifierQuoter and invalidates cached columns of SelectStmt.
+func (s *Storage) SetQuoter(quoter func(tableAndColumn ...string) string) {
+	s.IdentifierQuoter = quoter
+	s.invalidate()
+}
+
+// SetMapper sets Mapper and invalidates cached columns of SelectStmt.
+func (s *Storage) SetMapper(m *Mapper) {
+	s.Mapper = m
+	s.invalidate()
+}
+
+// invalidate switches Storage to a new generation of cached columns of SelectStmt.
+func (s *Storage) invalidate() {
+	atomic.StoreUint64(&s.generation, atomic.AddUint64(&generations, 1))
+}
+
+// ClearCache removes cached columns of SelectStmt and cached field mapping of Mapper.
+//
+// Columns of SelectStmt are cached per Mapper instance, number of cached types is limited with
+// Mapper.MaxCachedTypes. Cache needs to be cleared if IdentifierQuoter or Mapper are assigned directly
+// (instead of SetQuoter or SetMapper) or if settings of Mapper (e.g. Dialect or ColumnNameMapper) are changed
+// after Storage was used.
+func (s *Storage) ClearCache() {
+	s.invalidate()
+
+	if s.selectColumns != nil {
+		s.selectColumns.clear()
+	}
+
+	mapper(s.Mapper).ClearCache()
 }
 
 // InsertStmt makes an insert query builder.
@@ -314,7 +1288,37 @@ func (s *Storage) InsertStmt(tableName string, val interface{}, options ...func(
 
 	qb := s.QueryBuilder().Insert(tableName)
 
-	return mapper(s.Mapper).Insert(qb, val, s.options(options)...).RunWith(s.db)
+	return mapper(s.Mapper).Insert(qb, val, s.options(options)...)
+}
+
+// InsertSelectStmt makes an INSERT ... SELECT query builder with columns of a structure.
+//
+// Columns are mapped with same rules as in InsertStmt, select statement must provide values in the same order.
+//
+//	st.InsertSelectStmt("archive", Row{}, st.SelectStmt("rows", Row{}).Where(squirrel.Lt{"created_at": t}))
+func (s *Storage) InsertSelectStmt(
+	dstTable string,
+	columns interface{},
+	sel squirrel.SelectBuilder,
+	options ...func(*Options),
+) squirrel.InsertBuilder {
+	if s.IdentifierQuoter != nil {
+		dstTable = s.IdentifierQuoter(dstTable)
+	}
+
+	o := Options{}
+
+	for _, option := range s.options(options) {
+		option(&o)
+	}
+
+	o.SkipZeroValues = false
+	o.IgnoreOmitEmpty = true
+	o.statement = statementInsert
+
+	cols, _ := mapper(s.Mapper).columnsValues(reflect.ValueOf(columns), o)
+
+	return s.QueryBuilder().Insert(dstTable).Columns(cols...).Select(sel)
 }
 
 // UpdateStmt makes an update query builder.
@@ -325,7 +1329,7 @@ func (s *Storage) UpdateStmt(tableName string, val interface{}, options ...func(
 
 	qb := s.QueryBuilder().Update(tableName)
 
-	return mapper(s.Mapper).Update(qb, val, s.options(options)...).RunWith(s.db)
+	return mapper(s.Mapper).Update(qb, val, s.options(options)...)
 }
 
 // DeleteStmt makes a delete query builder.
@@ -334,7 +1338,7 @@ func (s *Storage) DeleteStmt(tableName string) squirrel.DeleteBuilder {
 		tableName = s.IdentifierQuoter(tableName)
 	}
 
-	return s.QueryBuilder().Delete(tableName).RunWith(s.db)
+	return s.QueryBuilder().Delete(tableName)
 }
 
 // Col will try to find column name and will panic on error.
@@ -347,6 +1351,22 @@ func (s *Storage) Col(structPtr, fieldPtr interface{}) string {
 	return col
 }
 
+// Q quotes identifier with IdentifierQuoter.
+func (s *Storage) Q(tableAndColumn ...string) Quoted {
+	return s.MakeReferencer().Q(tableAndColumn...)
+}
+
+// Expr formats SQL fragment with quoted identifiers and returns it as squirrel.Sqlizer with bind arguments.
+//
+// Quoted arguments are placed in format verbs, other arguments are bind arguments for `?` placeholders.
+//
+//	q.Where(st.Expr("%s > ?", st.Q("order"), 0))
+//
+// See Referencer.Expr for details.
+func (s *Storage) Expr(format string, ptrsOrArgs ...interface{}) squirrel.Sqlizer {
+	return s.MakeReferencer().Expr(format, ptrsOrArgs...)
+}
+
 // MakeReferencer creates Referencer for query builder.
 func (s *Storage) MakeReferencer() *Referencer {
 	return &Referencer{
@@ -360,6 +1380,80 @@ func (s *Storage) WhereEq(conditions interface{}, options ...func(*Options)) squ
 	return mapper(s.Mapper).WhereEq(conditions, s.options(options)...)
 }
 
+// WhereEqOrdered maps struct values as equality conditions to squirrel.And in column order of mapper.
+func (s *Storage) WhereEqOrdered(conditions interface{}, options ...func(*Options)) squirrel.Sqlizer {
+	return mapper(s.Mapper).WhereEqOrdered(conditions, s.options(options)...)
+}
+
+// Row makes a row value of structure fields in column order of Storage mapper, see Row.
+func (s *Storage) Row(structPtr interface{}, options ...func(*Options)) RowValue {
+	return mapper(s.Mapper).Row(structPtr, options...)
+}
+
+// Where maps struct values as conditions to squirrel.Sqlizer.
+func (s *Storage) Where(conditions interface{}, options ...func(*Options)) squirrel.Sqlizer {
+	return mapper(s.Mapper).Where(conditions, s.options(options)...)
+}
+
+// WhereEqAny maps slice of struct values as alternative conditions to squirrel.Or.
+func (s *Storage) WhereEqAny(conditions interface{}, options ...func(*Options)) squirrel.Or {
+	return mapper(s.Mapper).WhereEqAny(conditions, s.options(options)...)
+}
+
+// withComment prepends comment from context to the query.
+//
+// Comment delimiters are escaped, so that comment can not be terminated early.
+func (s *Storage) withComment(ctx context.Context, query string) string {
+	if s.CommentFromContext == nil {
+		return query
+	}
+
+	c := s.CommentFromContext(ctx)
+	if c == "" {
+		return query
+	}
+
+	c = strings.NewReplacer("*/", "* /", "/*", "/ *").Replace(c)
+
+	return "/*" + c + "*/ " + query
+}
+
+// trace instruments database call with Trace and Logger, RedactArgs is applied to arguments.
+func (s *Storage) trace(ctx context.Context, stmt string, args []interface{}) (context.Context, func(error)) {
+	if s.Trace == nil && s.Logger == nil {
+		return ctx, func(error) {}
+	}
+
+	var onFinish func(error)
+
+	args = s.traceArgs(stmt, args)
+
+	if s.Trace != nil {
+		ctx, onFinish = s.Trace(ctx, stmt, args)
+	}
+
+	logger := s.Logger
+	start := time.Now()
+
+	return ctx, func(err error) {
+		if onFinish != nil {
+			onFinish(err)
+		}
+
+		if logger != nil {
+			logger.Log(ctx, QueryInfo{SQL: stmt, Args: args, Duration: time.Since(start), Err: err})
+		}
+	}
+}
+
+func (s *Storage) traceArgs(stmt string, args []interface{}) []interface{} {
+	if s.RedactArgs == nil {
+		return args
+	}
+
+	return s.RedactArgs(stmt, append([]interface{}(nil), args...))
+}
+
 func (s *Storage) error(ctx context.Context, err error) error {
 	if err != nil && !errors.Is(err, sql.ErrNoRows) && s.OnError != nil {
 		s.OnError(ctx, err)
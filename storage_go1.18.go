@@ -8,15 +8,38 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+var (
+	errReturningNotSupported = errors.New("RETURNING is not supported")
+	errMissingCondition      = errors.New("missing condition")
+	errMissingPrimaryKey     = errors.New("missing primary key")
+	errMissingColumns        = errors.New("missing columns")
+	errExpressionValue       = errors.New("expression value can not be copied in column")
+	errInvalidKey            = errors.New("invalid key")
+	errNoRunningTx           = errors.New("no running transaction")
 )
 
 // SerialID is the name of field tag to indicate integer serial (auto increment) ID of the table.
 const SerialID = "serialIdentity"
 
+// PrimaryKey is the name of field tag to indicate a column of primary key of the table.
+//
+// Multiple fields can be tagged to define composite primary key, fields tagged with SerialID
+// are also considered as primary key columns.
+//
+//	UserID int `db:"user_id,pk"`
+//	RoleID int `db:"role_id,pk"`
+const PrimaryKey = "pk"
+
 // Get retrieves a single row from database storage.
 func Get[V any](ctx context.Context, s *Storage, qb ToSQL) (V, error) {
 	var v V
@@ -26,6 +49,23 @@ func Get[V any](ctx context.Context, s *Storage, qb ToSQL) (V, error) {
 	return v, err
 }
 
+// GetOptional retrieves a single row from database storage, nil is returned if row is not found.
+//
+// Unlike Get, sql.ErrNoRows is not returned.
+func GetOptional[V any](ctx context.Context, s *Storage, qb ToSQL) (*V, error) {
+	var v V
+
+	if err := s.Select(ctx, qb, &v); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil //nolint:nilnil // Missing row is not an error.
+		}
+
+		return nil, err
+	}
+
+	return &v, nil
+}
+
 // List retrieves a collection of rows from database storage.
 func List[V any](ctx context.Context, s *Storage, qb ToSQL) ([]V, error) {
 	var v []V
@@ -35,13 +75,197 @@ func List[V any](ctx context.Context, s *Storage, qb ToSQL) ([]V, error) {
 	return v, err
 }
 
+// MustGet retrieves a single row from database storage and panics on error.
+//
+// It is intended for tests and initialization code, production code should use Get.
+func MustGet[V any](ctx context.Context, s *Storage, qb ToSQL) V {
+	v, err := Get[V](ctx, s, qb)
+	if err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+// MustList retrieves a collection of rows from database storage and panics on error.
+//
+// It is intended for tests and initialization code, production code should use List.
+func MustList[V any](ctx context.Context, s *Storage, qb ToSQL) []V {
+	v, err := List[V](ctx, s, qb)
+	if err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+// Scalar retrieves a single value from database storage, e.g. result of aggregate function.
+//
+// Zero value is returned for NULL.
+func Scalar[T any](ctx context.Context, s *Storage, qb ToSQL) (T, error) {
+	v, _, err := ScalarOptional[T](ctx, s, qb)
+
+	return v, err
+}
+
+// ScalarOptional retrieves a single nullable value from database storage, e.g. result of aggregate function.
+//
+// Zero value and false are returned for NULL.
+func ScalarOptional[T any](ctx context.Context, s *Storage, qb ToSQL) (T, bool, error) {
+	var (
+		v    *T
+		zero T
+	)
+
+	if err := s.Select(ctx, qb, &v); err != nil {
+		return zero, false, err
+	}
+
+	if v == nil {
+		return zero, false, nil
+	}
+
+	return *v, true, nil
+}
+
+// DeleteReturning executes delete statement and returns deleted rows.
+//
+// RETURNING clause is added with columns of V, or with returningColumns if provided.
+// It is supported for Postgres, SQLite and MariaDB dialects, error is returned for MySQL.
+//
+//	deleted, err := sqluct.DeleteReturning[Order](ctx, st, st.DeleteStmt("orders").Where(squirrel.Lt{"created_at": t}))
+func DeleteReturning[V any](ctx context.Context, s *Storage, qb squirrel.DeleteBuilder, returningColumns ...string) ([]V, error) {
+	if d := mapper(s.Mapper).Dialect; d == DialectMySQL {
+		return nil, fmt.Errorf("%w for dialect %q", errReturningNotSupported, d)
+	}
+
+	cols := strings.Join(returningColumns, ", ")
+	if cols == "" {
+		var v V
+
+		cols = mapper(s.Mapper).ColumnsString(v, s.options(nil)...)
+	}
+
+	var rows []V
+
+	if err := s.Select(ReadFromPrimary(ctx), qb.Suffix("RETURNING "+cols), &rows); err != nil {
+		return nil, fmt.Errorf("delete: %w", err)
+	}
+
+	return rows, nil
+}
+
+// InTx runs callback in a transaction and returns its value.
+//
+// Transaction is handled same way as in Storage.InTx, zero value is returned with error.
+func InTx[T any](ctx context.Context, s *Storage, fn func(ctx context.Context) (T, error)) (T, error) {
+	var v T
+
+	err := s.InTx(ctx, func(ctx context.Context) error {
+		var err error
+
+		v, err = fn(ctx)
+
+		return err
+	})
+	if err != nil {
+		var zero T
+
+		return zero, err
+	}
+
+	return v, nil
+}
+
+// Alias allocates row structure, registers it in Referencer with table alias and returns its pointer.
+//
+//	manager := sqluct.Alias[User](rf, "manager")
+//	employee := sqluct.Alias[User](rf, "employee")
+//
+// It panics if Referencer is nil.
+func Alias[V any](rf *Referencer, alias string) *V {
+	if rf == nil {
+		panic("non-nil Referencer expected")
+	}
+
+	v := new(V)
+	rf.AddTableAlias(v, alias)
+
+	return v
+}
+
+// Projection selects columns and scans rows with the same structure P, so that they can not drift apart.
+type Projection[P any] struct {
+	s       *Storage
+	options []func(*Options)
+}
+
+// Project creates Projection of structure P in Storage.
+//
+// Options are applied to selected columns, e.g. sqluct.Columns or Referencer.ColumnsOf.
+//
+//	names := sqluct.Project[UserName](st)
+//	rows, err := names.List(ctx, names.SelectStmt("users").Where(squirrel.Eq{"active": true}))
+func Project[P any](s *Storage, options ...func(*Options)) Projection[P] {
+	return Projection[P]{s: s, options: options}
+}
+
+// Columns returns selected columns of P.
+func (p Projection[P]) Columns() []string {
+	var v P
+
+	o := Options{}
+
+	for _, option := range p.s.options(p.options) {
+		option(&o)
+	}
+
+	return mapper(p.s.Mapper).selectColumns(v, o)
+}
+
+// SelectStmt makes a select query builder with columns of P.
+func (p Projection[P]) SelectStmt(tableName string) squirrel.SelectBuilder {
+	var v P
+
+	return p.s.SelectStmt(tableName, v, p.options...)
+}
+
+// Select adds columns of P to a select query builder, e.g. a join query.
+func (p Projection[P]) Select(qb squirrel.SelectBuilder) squirrel.SelectBuilder {
+	return qb.Columns(p.Columns()...)
+}
+
+// List retrieves a collection of rows of P.
+func (p Projection[P]) List(ctx context.Context, qb ToSQL) ([]P, error) {
+	return List[P](ctx, p.s, qb)
+}
+
+// Get retrieves a single row of P.
+func (p Projection[P]) Get(ctx context.Context, qb ToSQL) (P, error) {
+	return Get[P](ctx, p.s, qb)
+}
+
 // StorageOf is a type-safe facade to work with rows of specific type.
 type StorageOf[V any] struct {
 	*Referencer
-	R         *V
+	R *V
+
+	// Partition optionally returns name of table partition for a row, e.g. "events_2024_01" by time field.
+	// It is used in InsertRow and InsertRows, rows are inserted in table name if Partition is nil.
+	Partition func(row V) string
+
 	s         *Storage
 	tableName string
 	id        string
+
+	// keyCols and keyPtrs are columns and field pointers of primary key in field order.
+	keyCols []string
+	keyPtrs []interface{}
+
+	// tx and txHooks are pinned with WithTx.
+	pinned  bool
+	tx      *sqlx.Tx
+	txHooks *txHooks
 }
 
 // Table configures and returns StorageOf in a table.
@@ -54,16 +278,24 @@ func Table[V any](storage *Storage, tableName string) StorageOf[V] {
 
 	ar.R = &v
 
-	tm := mapper(ar.s.Mapper).typeMap(reflect.TypeOf(v))
+	sm := mapper(ar.s.Mapper)
+
+	tm := sm.typeMap(reflect.TypeOf(v))
 	for _, fi := range tm.Index {
 		if fi.Embedded {
 			continue
 		}
 
-		if _, ok := fi.Options[SerialID]; ok {
-			ar.id = fi.Name
+		_, serial := fi.Options[SerialID]
+		_, pk := fi.Options[PrimaryKey]
 
-			break
+		if serial && ar.id == "" {
+			ar.id = sm.fieldColName(fi)
+		}
+
+		if serial || pk {
+			ar.keyCols = append(ar.keyCols, sm.fieldColName(fi))
+			ar.keyPtrs = append(ar.keyPtrs, reflectx.FieldByIndexes(reflect.ValueOf(ar.R).Elem(), fi.Index).Addr().Interface())
 		}
 	}
 
@@ -73,24 +305,177 @@ func Table[V any](storage *Storage, tableName string) StorageOf[V] {
 	return ar
 }
 
+// WithTx returns a copy of StorageOf that executes statements in transaction from context.
+//
+// Transaction is pinned, so that statements of returned StorageOf participate in it regardless of context
+// passed to its methods, this makes transactional boundaries explicit.
+// If there is no transaction in context, methods of returned StorageOf fail with error.
+//
+//	err := st.InTx(ctx, func(ctx context.Context) error {
+//		txUsers := users.WithTx(ctx)
+//		...
+//	})
+func (s *StorageOf[V]) WithTx(ctx context.Context) StorageOf[V] {
+	res := *s
+	res.pinned = true
+	res.tx = TxFromContext(ctx)
+	res.txHooks = txHooksFromContext(ctx)
+
+	return res
+}
+
+// txContext returns context with transaction pinned with WithTx.
+func (s *StorageOf[V]) txContext(ctx context.Context) (context.Context, error) {
+	if !s.pinned || (s.tx != nil && TxFromContext(ctx) == s.tx) {
+		return ctx, nil
+	}
+
+	if s.tx == nil {
+		return ctx, fmt.Errorf("%w for table %q", errNoRunningTx, s.tableName)
+	}
+
+	ctx = TxToContext(ctx, s.tx)
+
+	if s.txHooks != nil {
+		ctx = context.WithValue(ctx, txHooksCtxKey{}, s.txHooks)
+	}
+
+	return ctx, nil
+}
+
 // List retrieves a collection of rows from database storage.
 func (s *StorageOf[V]) List(ctx context.Context, qb ToSQL) ([]V, error) {
 	var v []V
 
-	err := s.s.Select(ctx, qb, &v)
+	ctx, err := s.txContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.s.Select(ctx, qb, &v)
 
 	return v, err
 }
 
+// ListPtr retrieves a collection of rows from database storage as pointers.
+//
+// Unlike List, rows are not copied into a slice of values, that can be beneficial for large structures.
+func (s *StorageOf[V]) ListPtr(ctx context.Context, qb ToSQL) ([]*V, error) {
+	var v []*V
+
+	ctx, err := s.txContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.s.Select(ctx, qb, &v)
+
+	return v, err
+}
+
+var (
+	// ErrInvalidPage is returned by StorageOf.ListPage for zero page number.
+	ErrInvalidPage = errors.New("invalid page number")
+
+	// ErrInvalidPerPage is returned by StorageOf.ListPage if both perPage and maxPerPage are zero.
+	ErrInvalidPerPage = errors.New("invalid number of rows per page")
+)
+
+// Page describes applied pagination.
+type Page struct {
+	Number uint64
+	Limit  uint64
+	Offset uint64
+}
+
+// ListPage retrieves a page of rows from database storage.
+//
+// Pages are numbered from 1, ErrInvalidPage is returned for page 0.
+// Number of rows per page is clamped to maxPerPage, zero perPage means maxPerPage, zero maxPerPage means no clamping.
+// ErrInvalidPerPage is returned if both perPage and maxPerPage are zero.
+// Applied pagination is returned to help building pagination metadata.
+func (s *StorageOf[V]) ListPage(ctx context.Context, qb squirrel.SelectBuilder, page, perPage, maxPerPage uint64) ([]V, Page, error) {
+	if page == 0 {
+		return nil, Page{}, ErrInvalidPage
+	}
+
+	if perPage == 0 || (maxPerPage > 0 && perPage > maxPerPage) {
+		perPage = maxPerPage
+	}
+
+	if perPage == 0 {
+		return nil, Page{}, ErrInvalidPerPage
+	}
+
+	p := Page{Number: page, Limit: perPage, Offset: (page - 1) * perPage}
+
+	qb = qb.Limit(p.Limit)
+
+	if p.Offset > 0 {
+		qb = qb.Offset(p.Offset)
+	}
+
+	rows, err := s.List(ctx, qb)
+	if err != nil {
+		return nil, p, err
+	}
+
+	return rows, p, nil
+}
+
 // Get retrieves a single row from database storage.
+//
+// NotFoundError is returned if row is not found.
 func (s *StorageOf[V]) Get(ctx context.Context, qb ToSQL) (V, error) {
 	var v V
 
-	err := s.s.Select(ctx, qb, &v)
+	ctx, err := s.txContext(ctx)
+	if err != nil {
+		return v, err
+	}
+
+	err = s.s.Select(ctx, qb, &v)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = NotFoundError{Table: s.tableName}
+	}
 
 	return v, err
 }
 
+// Count returns number of rows matching select query, see Storage.Count.
+func (s *StorageOf[V]) Count(ctx context.Context, qb squirrel.SelectBuilder) (int64, error) {
+	ctx, err := s.txContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.s.Count(ctx, qb)
+}
+
+// CountDistinct returns number of distinct values of field in rows matching select query, see Storage.CountDistinct.
+//
+//	cnt, err := s.CountDistinct(ctx, s.SelectStmt(), &s.R.UserID)
+//
+// Error is returned if field pointer is unknown.
+func (s *StorageOf[V]) CountDistinct(ctx context.Context, qb squirrel.SelectBuilder, fieldPtr interface{}) (int64, error) {
+	ctx, err := s.txContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.s.CountDistinct(ctx, qb, s.Referencer, fieldPtr)
+}
+
+// GetOptional retrieves a single row from database storage, nil is returned if row is not found.
+func (s *StorageOf[V]) GetOptional(ctx context.Context, qb ToSQL) (*V, error) {
+	ctx, err := s.txContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetOptional[V](ctx, s.s, qb)
+}
+
 // SelectStmt creates query statement with table name and row columns.
 func (s *StorageOf[V]) SelectStmt(options ...func(*Options)) squirrel.SelectBuilder {
 	if len(options) == 0 {
@@ -102,6 +487,49 @@ func (s *StorageOf[V]) SelectStmt(options ...func(*Options)) squirrel.SelectBuil
 	return s.s.SelectStmt(s.tableName, s.R, options...)
 }
 
+// SelectFields creates query statement with table name and columns of field pointers of R.
+//
+// Result can be scanned into V with List or Get, fields that are not selected keep zero values.
+// Alternatively, it can be scanned into a projection struct with sqluct.List or sqluct.Get,
+// the projection struct must have fields for all selected columns.
+//
+//	users, err := ur.List(ctx, ur.SelectFields(&ur.R.ID, &ur.R.Name).Where(ur.Eq(&ur.R.RoleID, 1)))
+func (s *StorageOf[V]) SelectFields(ptrs ...interface{}) squirrel.SelectBuilder {
+	return s.Select(s.s.QueryBuilder().Select().From(string(s.Q(s.tableName))), ptrs...)
+}
+
+// Where maps filter struct values as conditions on table columns prefixed with table name.
+//
+// Filter struct is decoupled from row type, but its columns (or target columns of operators,
+// e.g. `db:"created_from,ge=created_at"`) must exist in row type, otherwise resulting condition fails to build.
+func (s *StorageOf[V]) Where(filter interface{}, options ...func(*Options)) squirrel.Sqlizer {
+	sm := mapper(s.s.Mapper)
+	cols := sm.Columns(s.R)
+
+	fcols, err := sm.filterColumns(filter)
+	if err != nil {
+		return errStmt{err: err}
+	}
+
+	for _, fc := range fcols {
+		found := false
+
+		for _, c := range cols {
+			if c.Name == fc {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return errStmt{err: fmt.Errorf("%w %q in filter for table %q", errUnknownColumn, fc, s.tableName)}
+		}
+	}
+
+	return s.s.Where(filter, append([]func(*Options){s.ColumnsOf(s.R)}, options...)...)
+}
+
 // DeleteStmt creates delete statement with table name.
 func (s *StorageOf[V]) DeleteStmt() squirrel.DeleteBuilder {
 	return s.s.DeleteStmt(s.tableName)
@@ -112,21 +540,298 @@ func (s *StorageOf[V]) UpdateStmt(value any, options ...func(*Options)) squirrel
 	return s.s.UpdateStmt(s.tableName, value, options...)
 }
 
-// InsertRow inserts single row database table.
-func (s *StorageOf[V]) InsertRow(ctx context.Context, row V, options ...func(o *Options)) (int64, error) {
-	q := s.s.InsertStmt(s.tableName, row, options...)
+// Exec executes query according to query builder, transaction from context is used if available.
+func (s *StorageOf[V]) Exec(ctx context.Context, qb ToSQL) (sql.Result, error) {
+	ctx, err := s.txContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.s.Exec(ctx, qb)
+}
+
+// UpdateFields updates columns of field pointers with values in rows matching conditions.
+//
+// Unlike UpdateStmt with a struct value, only assigned fields are updated, zero values are not skipped.
+//
+//	err := s.UpdateFields(ctx, map[any]any{&s.R.Name: "John"}, s.Eq(&s.R.ID, 123))
+func (s *StorageOf[V]) UpdateFields(ctx context.Context, assignments map[any]any, cond ...squirrel.Sqlizer) error {
+	set := make(map[string]interface{}, len(assignments))
+
+	for ptr, val := range assignments {
+		set[s.Ref(NoTable(ptr))] = val
+	}
+
+	q := s.s.UpdateStmt(s.tableName, nil).SetMap(set)
+
+	for _, c := range cond {
+		q = q.Where(c)
+	}
 
-	if mapper(s.s.Mapper).Dialect == DialectPostgres && s.id != "" {
-		q = q.Suffix("RETURNING " + s.id)
+	if _, err := s.Exec(ctx, q); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	return nil
+}
+
+// Increment adds delta to column of field pointer in rows matching conditions and returns number of affected rows.
+//
+// Negative delta decrements the column, at least one condition is required to avoid updating all rows.
+//
+//	affected, err := s.Increment(ctx, &s.R.Views, 1, s.Eq(&s.R.ID, 123))
+func (s *StorageOf[V]) Increment(ctx context.Context, fieldPtr interface{}, delta interface{}, cond ...squirrel.Sqlizer) (int64, error) {
+	if len(cond) == 0 {
+		return 0, fmt.Errorf("increment: %w", errMissingCondition)
+	}
+
+	q := s.s.UpdateStmt(s.tableName, nil).Set(s.Incr(fieldPtr, delta))
+
+	for _, c := range cond {
+		q = q.Where(c)
+	}
+
+	return s.affected(ctx, "increment", q)
+}
+
+// UpdateRow updates columns of row value in rows matching condition and returns number of affected rows.
+//
+// Columns are mapped same way as in UpdateStmt, nil condition is not allowed to avoid updating all rows.
+//
+//	affected, err := s.UpdateRow(ctx, user, s.Eq(&s.R.ID, user.ID))
+func (s *StorageOf[V]) UpdateRow(ctx context.Context, row V, cond squirrel.Sqlizer, options ...func(*Options)) (int64, error) {
+	if cond == nil {
+		return 0, fmt.Errorf("update: %w", errMissingCondition)
+	}
 
-		query, args, err := q.ToSql()
+	return s.affected(ctx, "update", s.s.UpdateStmt(s.tableName, row, options...).Where(cond))
+}
+
+// DeleteWhere deletes rows matching conditions and returns number of affected rows.
+//
+// At least one condition is required to avoid deleting all rows.
+//
+//	affected, err := s.DeleteWhere(ctx, s.Eq(&s.R.ID, 123))
+func (s *StorageOf[V]) DeleteWhere(ctx context.Context, cond ...squirrel.Sqlizer) (int64, error) {
+	if len(cond) == 0 {
+		return 0, fmt.Errorf("delete: %w", errMissingCondition)
+	}
+
+	q := s.s.DeleteStmt(s.tableName)
+
+	for _, c := range cond {
+		q = q.Where(c)
+	}
+
+	return s.affected(ctx, "delete", q)
+}
+
+// WhereKey makes condition on primary key columns.
+//
+// Key can be defined with values of primary key columns in field order, or with a single struct value
+// that has fields of all primary key columns (e.g. row value V or a dedicated key struct).
+//
+//	s.WhereKey(123)                             // "users"."id" = $1
+//	s.WhereKey(userID, roleID)                  // "user_roles"."user_id" = $1 AND "user_roles"."role_id" = $2
+//	s.WhereKey(UserRole{UserID: 1, RoleID: 2}) // same, values are taken from struct fields
+//
+// Condition fails to build if table has no primary key or key does not match it.
+func (s *StorageOf[V]) WhereKey(key ...interface{}) squirrel.Sqlizer {
+	if len(s.keyCols) == 0 {
+		return errStmt{err: fmt.Errorf("%w in table %q", errMissingPrimaryKey, s.tableName)}
+	}
+
+	if len(key) == 1 {
+		if k, ok, err := s.structKey(key[0]); err != nil {
+			return errStmt{err: err}
+		} else if ok {
+			key = k
+		}
+	}
+
+	if len(key) != len(s.keyCols) {
+		return errStmt{err: fmt.Errorf("%w: %d values expected for table %q, %d received",
+			errInvalidKey, len(s.keyCols), s.tableName, len(key))}
+	}
+
+	and := make(squirrel.And, 0, len(key))
+
+	for i, ptr := range s.keyPtrs {
+		and = append(and, s.Eq(ptr, key[i]))
+	}
+
+	return and
+}
+
+// structKey returns values of primary key columns from struct, false is returned if value is not a struct
+// or has no primary key columns.
+func (s *StorageOf[V]) structKey(key interface{}) ([]interface{}, bool, error) {
+	v := reflect.Indirect(reflect.ValueOf(key))
+	if v.Kind() != reflect.Struct {
+		return nil, false, nil
+	}
+
+	cols, vals := mapper(s.s.Mapper).columnsValues(v, Options{Columns: s.keyCols, IgnoreOmitEmpty: true})
+	if len(cols) == 0 {
+		return nil, false, nil
+	}
+
+	res := make([]interface{}, len(s.keyCols))
+
+	for i, kc := range s.keyCols {
+		found := false
+
+		for j, c := range cols {
+			if c == kc {
+				res[i] = vals[j]
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return nil, false, fmt.Errorf("%w: missing column %q in %T", errInvalidKey, kc, key)
+		}
+	}
+
+	return res, true, nil
+}
+
+// GetByID retrieves a single row by primary key, see WhereKey for key definition.
+//
+// NotFoundError is returned if row is not found.
+//
+//	role, err := s.GetByID(ctx, userID, roleID)
+func (s *StorageOf[V]) GetByID(ctx context.Context, key ...interface{}) (V, error) {
+	return s.Get(ctx, s.SelectStmt().Where(s.WhereKey(key...)))
+}
+
+// UpdateByID updates columns of row value in a row with same primary key and returns number of affected rows.
+//
+// Columns are mapped same way as in UpdateStmt.
+//
+//	affected, err := s.UpdateByID(ctx, userRole)
+func (s *StorageOf[V]) UpdateByID(ctx context.Context, row V, options ...func(*Options)) (int64, error) {
+	return s.UpdateRow(ctx, row, s.WhereKey(row), options...)
+}
+
+// DeleteByID deletes a row by primary key and returns number of affected rows, see WhereKey for key definition.
+//
+//	affected, err := s.DeleteByID(ctx, userID, roleID)
+func (s *StorageOf[V]) DeleteByID(ctx context.Context, key ...interface{}) (int64, error) {
+	return s.DeleteWhere(ctx, s.WhereKey(key...))
+}
+
+// DeleteByIDs deletes rows by primary keys and returns total number of affected rows.
+//
+// For a single column primary key, ids are values of the column, for composite primary key, ids are
+// key structs, see WhereKey. Rows are deleted with statements in chunks to respect limit of bind arguments,
+// chunks are executed in a transaction from context or in a new one.
+//
+// Empty ids is a no-op.
+//
+//	affected, err := s.DeleteByIDs(ctx, 1, 2, 3)
+func (s *StorageOf[V]) DeleteByIDs(ctx context.Context, ids ...interface{}) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	if len(s.keyCols) == 0 {
+		return 0, fmt.Errorf("delete: %w in table %q", errMissingPrimaryKey, s.tableName)
+	}
+
+	ctx, err := s.txContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var affected int64
+
+	err = s.s.InTx(ctx, func(ctx context.Context) error {
+		chunk := maxBindArgs / len(s.keyCols)
+
+		for start := 0; start < len(ids); start += chunk {
+			end := start + chunk
+			if end > len(ids) {
+				end = len(ids)
+			}
+
+			n, err := s.affected(ctx, fmt.Sprintf("delete ids %d-%d", start, end-1),
+				s.s.DeleteStmt(s.tableName).Where(s.whereKeys(ids[start:end])))
+			if err != nil {
+				return err
+			}
+
+			affected += n
+		}
+
+		return nil
+	})
+
+	return affected, err
+}
+
+// whereKeys makes condition on multiple primary keys.
+func (s *StorageOf[V]) whereKeys(ids []interface{}) squirrel.Sqlizer {
+	if len(s.keyCols) > 1 {
+		or := make(squirrel.Or, 0, len(ids))
+
+		for _, id := range ids {
+			or = append(or, s.WhereKey(id))
+		}
+
+		return or
+	}
+
+	vals := make([]interface{}, 0, len(ids))
+
+	for _, id := range ids {
+		k, ok, err := s.structKey(id)
 		if err != nil {
-			return 0, fmt.Errorf("building insert statement: %w", err)
+			return errStmt{err: err}
+		}
+
+		if ok {
+			id = k[0]
 		}
 
+		vals = append(vals, id)
+	}
+
+	return s.Eq(s.keyPtrs[0], vals)
+}
+
+func (s *StorageOf[V]) affected(ctx context.Context, op string, qb ToSQL) (int64, error) {
+	res, err := s.Exec(ctx, qb)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s rows affected: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// InsertRow inserts single row database table.
+//
+// Serial ID of inserted row is returned if row has a field tagged with SerialID, it is retrieved with
+// RETURNING clause for Postgres and MariaDB dialects and with LastInsertId for others.
+func (s *StorageOf[V]) InsertRow(ctx context.Context, row V, options ...func(o *Options)) (int64, error) {
+	ctx, err := s.txContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	q := s.s.InsertStmt(s.insertTable(row), row, options...)
+
+	if d := mapper(s.s.Mapper).Dialect; (d == DialectPostgres || d == DialectMariaDB) && s.id != "" {
 		var id int64
 
-		if err = s.s.db.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		if err = s.s.Select(ReadFromPrimary(ctx), q.Suffix("RETURNING "+s.id), &id); err != nil {
 			return 0, fmt.Errorf("insert: %w", err)
 		}
 
@@ -151,8 +856,66 @@ func (s *StorageOf[V]) InsertRow(ctx context.Context, row V, options ...func(o *
 }
 
 // InsertRows inserts multiple rows in database table.
+//
+// If Partition is set, rows are grouped by partition and inserted with a statement per partition in a transaction,
+// rows affected are summed in result, last insert id is of the last statement.
 func (s *StorageOf[V]) InsertRows(ctx context.Context, rows []V, options ...func(o *Options)) (sql.Result, error) {
-	q := s.s.InsertStmt(s.tableName, rows, options...)
+	ctx, err := s.txContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Partition == nil || len(rows) == 0 {
+		return s.insertRows(ctx, s.tableName, rows, options)
+	}
+
+	tables, groups := s.partitions(rows)
+
+	if len(tables) == 1 {
+		return s.insertRows(ctx, tables[0], rows, options)
+	}
+
+	res := make(results, 0, len(tables))
+
+	err = s.s.InTx(ctx, func(ctx context.Context) error {
+		for _, t := range tables {
+			r, err := s.insertRows(ctx, t, groups[t], options)
+			if err != nil {
+				return err
+			}
+
+			res = append(res, r)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// InsertRowsN inserts multiple rows in database table and returns number of inserted rows.
+//
+// Number of inserted rows is taken from rows affected, if database driver can not report it (error or -1),
+// number of rows is returned as a best-effort count.
+func (s *StorageOf[V]) InsertRowsN(ctx context.Context, rows []V, options ...func(o *Options)) (int, error) {
+	res, err := s.InsertRows(ctx, rows, options...)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil || n < 0 {
+		return len(rows), nil //nolint:nilerr // Unknown rows affected are not an error.
+	}
+
+	return int(n), nil
+}
+
+func (s *StorageOf[V]) insertRows(ctx context.Context, table string, rows []V, options []func(o *Options)) (sql.Result, error) {
+	q := s.s.InsertStmt(table, rows, options...)
 
 	res, err := s.s.Exec(ctx, q)
 	if err != nil {
@@ -162,6 +925,229 @@ func (s *StorageOf[V]) InsertRows(ctx context.Context, rows []V, options ...func
 	return res, nil
 }
 
+// partitions groups rows by table or partition name, names are in order of first occurrence.
+func (s *StorageOf[V]) partitions(rows []V) ([]string, map[string][]V) {
+	if s.Partition == nil {
+		return []string{s.tableName}, map[string][]V{s.tableName: rows}
+	}
+
+	var (
+		tables []string
+		groups = make(map[string][]V)
+	)
+
+	for _, row := range rows {
+		t := s.Partition(row)
+		if _, ok := groups[t]; !ok {
+			tables = append(tables, t)
+		}
+
+		groups[t] = append(groups[t], row)
+	}
+
+	return tables, groups
+}
+
+// insertTable returns table name or partition name for a row.
+func (s *StorageOf[V]) insertTable(row V) string {
+	if s.Partition == nil {
+		return s.tableName
+	}
+
+	return s.Partition(row)
+}
+
+// results combines results of multiple statements.
+type results []sql.Result
+
+// LastInsertId returns last insert id of the last statement.
+func (r results) LastInsertId() (int64, error) {
+	return r[len(r)-1].LastInsertId()
+}
+
+// RowsAffected returns total rows affected by statements, or -1 if any statement has unknown rows affected.
+func (r results) RowsAffected() (int64, error) {
+	var total int64
+
+	for _, res := range r {
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		if n < 0 {
+			return n, nil
+		}
+
+		total += n
+	}
+
+	return total, nil
+}
+
+// maxBindArgs is a conservative limit of bind arguments in a single statement (default of SQLite).
+const maxBindArgs = 32766
+
+// CopyFrom loads rows into database table and returns number of loaded rows.
+//
+// For Postgres dialect with lib/pq driver (registered as "postgres"), rows are streamed with COPY FROM STDIN
+// in a transaction using lib/pq protocol: statement is prepared, executed for every row and once without
+// arguments to flush. Otherwise (other dialects and drivers, e.g. pgx, or UseDefault and ValueExprs options),
+// rows are inserted with multi-row INSERT statements in chunks in a transaction.
+//
+// All columns (except ReadOnly and Auto) are loaded regardless of omitempty, in the order of mapper.
+// If Partition is set, rows are grouped and loaded into partitions in a single transaction.
+func (s *StorageOf[V]) CopyFrom(ctx context.Context, rows []V) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	ctx, err := s.txContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	o := Options{}
+
+	for _, option := range s.s.options([]func(*Options){allColumns}) {
+		option(&o)
+	}
+
+	o.statement = statementInsert
+
+	sm := mapper(s.s.Mapper)
+	cols, _ := sm.columnsValues(reflect.ValueOf(rows[0]), o)
+
+	if len(cols) == 0 {
+		return 0, fmt.Errorf("%w to copy in table %q", errMissingColumns, s.tableName)
+	}
+
+	useCopy := sm.Dialect == DialectPostgres && s.s.db != nil && s.s.db.DriverName() == "postgres" &&
+		len(o.UseDefault) == 0 && len(o.ValueExprs) == 0
+
+	tables, groups := s.partitions(rows)
+
+	var affected int64
+
+	err = s.s.InTx(ctx, func(ctx context.Context) error {
+		for _, t := range tables {
+			var (
+				n   int64
+				err error
+			)
+
+			if useCopy {
+				n, err = s.copyIn(ctx, t, groups[t], cols, o)
+			} else {
+				n, err = s.insertChunks(ctx, t, groups[t], len(cols))
+			}
+
+			if err != nil {
+				return err
+			}
+
+			affected += n
+		}
+
+		return nil
+	})
+
+	return affected, err
+}
+
+// insertChunks inserts rows with multi-row statements, each statement is limited by maxBindArgs.
+func (s *StorageOf[V]) insertChunks(ctx context.Context, table string, rows []V, numCols int) (int64, error) {
+	var affected int64
+
+	chunk := maxBindArgs / numCols
+
+	for start := 0; start < len(rows); start += chunk {
+		end := start + chunk
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		res, err := s.s.Exec(ctx, s.s.InsertStmt(table, rows[start:end], allColumns))
+		if err != nil {
+			return affected, fmt.Errorf("insert rows %d-%d: %w", start, end-1, err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return affected, fmt.Errorf("insert rows affected: %w", err)
+		}
+
+		affected += n
+	}
+
+	return affected, nil
+}
+
+// allColumns is an option to map all columns regardless of omitempty and zero values.
+func allColumns(o *Options) {
+	o.SkipZeroValues = false
+	o.IgnoreOmitEmpty = true
+}
+
+func (s *StorageOf[V]) copyIn(ctx context.Context, table string, rows []V, cols []string, o Options) (affected int64, err error) {
+	if s.s.IdentifierQuoter != nil {
+		table = s.s.IdentifierQuoter(table)
+	}
+
+	// Comment from context is not added, lib/pq only recognizes statements starting with COPY.
+	query := "COPY " + table + " (" + strings.Join(cols, ", ") + ") FROM STDIN"
+
+	ctx, finish := s.s.trace(ctx, query, nil)
+	defer func() { finish(err) }()
+
+	sm := mapper(s.s.Mapper)
+
+	err = s.s.InTx(ctx, func(ctx context.Context) error {
+		stmt, err := TxFromContext(ctx).PrepareContext(ctx, query)
+		if err != nil {
+			return fmt.Errorf("prepare copy: %w", err)
+		}
+
+		for i := range rows {
+			_, vals := sm.columnsValues(reflect.ValueOf(rows[i]), o)
+
+			for j, v := range vals {
+				if _, ok := v.(squirrel.Sqlizer); ok {
+					_ = stmt.Close()
+
+					return fmt.Errorf("copy row %d: %w %q", i, errExpressionValue, cols[j])
+				}
+			}
+
+			if _, err := stmt.ExecContext(ctx, vals...); err != nil {
+				_ = stmt.Close()
+
+				return fmt.Errorf("copy row %d: %w", i, err)
+			}
+		}
+
+		res, err := stmt.ExecContext(ctx)
+		if err != nil {
+			_ = stmt.Close()
+
+			return fmt.Errorf("copy flush: %w", err)
+		}
+
+		if err := stmt.Close(); err != nil {
+			return fmt.Errorf("copy close: %w", err)
+		}
+
+		affected, err = res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("copy rows affected: %w", err)
+		}
+
+		return nil
+	})
+
+	return affected, err
+}
+
 // JSON is a generic container to a serialized db column.
 type JSON[V any] struct {
 	Val V
@@ -5,10 +5,14 @@ package sqluct_test
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Masterminds/squirrel"
 	"github.com/bool64/sqluct"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
@@ -69,6 +73,68 @@ func TestList(t *testing.T) {
 	assert.True(t, traceFinished)
 }
 
+func TestList_pointers(t *testing.T) {
+	type row struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	rr := sqluct.Table[row](st, "rows")
+	ctx := context.Background()
+
+	expectRows := func() {
+		mock.ExpectQuery("SELECT rows.id, rows.name FROM rows").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+	}
+
+	assertRows := func(t *testing.T, rows []*row) {
+		t.Helper()
+
+		require.Len(t, rows, 2)
+		assert.Equal(t, row{ID: 1, Name: "a"}, *rows[0])
+		assert.Equal(t, row{ID: 2, Name: "b"}, *rows[1])
+		assert.NotSame(t, rows[0], rows[1])
+	}
+
+	expectRows()
+
+	var dest []*row
+
+	require.NoError(t, st.Select(ctx, rr.SelectStmt(), &dest))
+	assertRows(t, dest)
+
+	expectRows()
+
+	rows, err := sqluct.List[*row](ctx, st, rr.SelectStmt())
+	require.NoError(t, err)
+	assertRows(t, rows)
+
+	expectRows()
+
+	rows, err = rr.ListPtr(ctx, rr.SelectStmt())
+	require.NoError(t, err)
+	assertRows(t, rows)
+
+	st.ScanMode = sqluct.ScanStrict
+
+	expectRows()
+
+	rows, err = rr.ListPtr(ctx, rr.SelectStmt())
+	require.NoError(t, err)
+	assertRows(t, rows)
+
+	mock.ExpectQuery("SELECT rows.id FROM rows").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	_, err = rr.ListPtr(ctx, rr.SelectFields(&rr.R.ID))
+	require.EqualError(t, err, "missing result columns")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGet(t *testing.T) {
 	type row struct {
 		One   int `db:"one"`
@@ -94,6 +160,142 @@ func TestGet(t *testing.T) {
 	assert.Equal(t, row{One: 1, Two: 2, Three: 3}, item)
 }
 
+func TestGetOptional(t *testing.T) {
+	type row struct {
+		One int `db:"one"`
+	}
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+	qb := st.SelectStmt("table", row{})
+	ctx := context.Background()
+
+	mock.ExpectQuery("SELECT one FROM table").WillReturnRows(sqlmock.NewRows([]string{"one"}).AddRow(1))
+	mock.ExpectQuery("SELECT one FROM table").WillReturnRows(sqlmock.NewRows([]string{"one"}))
+	mock.ExpectQuery("SELECT one FROM table").WillReturnError(errors.New("failed"))
+
+	item, err := sqluct.GetOptional[row](ctx, st, qb)
+	require.NoError(t, err)
+	assert.Equal(t, &row{One: 1}, item)
+
+	tr := sqluct.Table[row](st, "table")
+
+	item, err = tr.GetOptional(ctx, qb)
+	require.NoError(t, err)
+	assert.Nil(t, item)
+
+	item, err = sqluct.GetOptional[row](ctx, st, qb)
+	require.EqualError(t, err, "failed")
+	assert.Nil(t, item)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMustGet(t *testing.T) {
+	type row struct {
+		ID int `db:"id"`
+	}
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	qb := st.SelectStmt("rows", row{})
+	ctx := context.Background()
+
+	mock.ExpectQuery("SELECT id FROM rows").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM rows").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectQuery("SELECT id FROM rows").WillReturnError(errors.New("failed"))
+	mock.ExpectQuery("SELECT id FROM rows").WillReturnError(errors.New("failed"))
+
+	assert.Equal(t, row{ID: 1}, sqluct.MustGet[row](ctx, st, qb))
+	assert.Equal(t, []row{{ID: 1}, {ID: 2}}, sqluct.MustList[row](ctx, st, qb))
+	assert.PanicsWithError(t, "failed", func() { sqluct.MustGet[row](ctx, st, qb) })
+	assert.PanicsWithError(t, "failed", func() { sqluct.MustList[row](ctx, st, qb) })
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(123, 1))
+	mock.ExpectCommit()
+
+	id, err := sqluct.InTx(ctx, st, func(ctx context.Context) (int64, error) {
+		res, err := st.Exec(ctx, sqluct.Plain("INSERT INTO users (name) VALUES ('John')"))
+		if err != nil {
+			return 0, err
+		}
+
+		return res.LastInsertId()
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(123), id)
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	id, err = sqluct.InTx(ctx, st, func(ctx context.Context) (int64, error) {
+		return 1, errors.New("failed")
+	})
+	require.EqualError(t, err, "failed")
+	assert.Equal(t, int64(0), id)
+
+	mock.ExpectBegin()
+	mock.ExpectCommit().WillReturnError(errors.New("conflict"))
+
+	id, err = sqluct.InTx(ctx, st, func(ctx context.Context) (int64, error) {
+		return 1, nil
+	})
+	require.EqualError(t, err, "failed to commit: conflict")
+	assert.Equal(t, int64(0), id)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestScalar(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	qb := st.QueryBuilder().Select("SUM(amount)").From("orders")
+	ctx := context.Background()
+
+	mock.ExpectQuery(`SELECT SUM\(amount\) FROM orders`).WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(100))
+	mock.ExpectQuery(`SELECT SUM\(amount\) FROM orders`).WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(nil))
+	mock.ExpectQuery(`SELECT SUM\(amount\) FROM orders`).WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(nil))
+	mock.ExpectQuery(`SELECT SUM\(amount\) FROM orders`).WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(100))
+	mock.ExpectQuery(`SELECT SUM\(amount\) FROM orders`).WillReturnError(errors.New("failed"))
+
+	sum, err := sqluct.Scalar[int64](ctx, st, qb)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), sum)
+
+	sum, err = sqluct.Scalar[int64](ctx, st, qb)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), sum)
+
+	sum, found, err := sqluct.ScalarOptional[int64](ctx, st, qb)
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, int64(0), sum)
+
+	sum, found, err = sqluct.ScalarOptional[int64](ctx, st, qb)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, int64(100), sum)
+
+	_, err = sqluct.Scalar[int64](ctx, st, qb)
+	require.EqualError(t, err, "failed")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestJSON_Value(t *testing.T) {
 	type nested struct {
 		A int  `json:"a"`
@@ -143,3 +345,996 @@ func TestJSON_Value(t *testing.T) {
 	_, err = st.InsertStmt("table", r).ExecContext(ctx)
 	require.NoError(t, err)
 }
+
+func TestStorageOf_Where(t *testing.T) {
+	st := sqluct.NewStorage(nil)
+	st.IdentifierQuoter = sqluct.QuoteANSI
+
+	type User struct {
+		ID     int    `db:"id"`
+		RoleID int    `db:"role_id"`
+		Name   string `db:"name"`
+	}
+
+	type UserFilter struct {
+		RoleIDs []int   `db:"role_id,omitempty"`
+		Name    *string `db:"name,omitempty"`
+	}
+
+	ur := sqluct.Table[User](st, "users")
+	name := "John"
+
+	stmt, args, err := ur.SelectStmt().Where(ur.Where(UserFilter{RoleIDs: []int{1, 2}, Name: &name})).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT "users"."id", "users"."role_id", "users"."name" FROM "users" WHERE ("users"."role_id" IN ($1,$2) AND "users"."name" = $3)`, stmt)
+	assert.Equal(t, []interface{}{1, 2, "John"}, args)
+
+	stmt, args, err = ur.SelectStmt().Where(ur.Where(UserFilter{})).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT "users"."id", "users"."role_id", "users"."name" FROM "users"`, stmt)
+	assert.Empty(t, args)
+
+	_, _, err = ur.SelectStmt().Where(ur.Where(struct {
+		Email string `db:"email"`
+	}{})).ToSql()
+	require.EqualError(t, err, `unknown column "email" in filter for table "users"`)
+}
+
+func TestStorageOf_Where_operators(t *testing.T) {
+	st := sqluct.NewStorage(nil)
+
+	type Row struct {
+		ID        int       `db:"id"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+
+	type RowFilter struct {
+		CreatedFrom time.Time `db:"created_from,ge=created_at,omitempty"`
+		CreatedTo   time.Time `db:"created_to,lt=created_at,omitempty"`
+		MinID       int       `db:"id,range=min,omitempty"`
+	}
+
+	rr := sqluct.Table[Row](st, "rows")
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	stmt, args, err := rr.SelectStmt().Where(rr.Where(RowFilter{CreatedFrom: ts, CreatedTo: ts, MinID: 10})).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT rows.id, rows.created_at FROM rows `+
+		`WHERE (rows.created_at >= $1 AND rows.created_at < $2 AND rows.id >= $3)`, stmt)
+	assert.Equal(t, []interface{}{ts, ts, 10}, args)
+
+	_, _, err = rr.SelectStmt().Where(rr.Where(struct {
+		UpdatedFrom time.Time `db:"updated_from,ge=updated_at"`
+	}{})).ToSql()
+	require.EqualError(t, err, `unknown column "updated_at" in filter for table "rows"`)
+
+	_, _, err = rr.SelectStmt().Where(rr.Where(struct {
+		CreatedFrom time.Time `db:"created_from,gte=created_at"`
+	}{})).ToSql()
+	require.EqualError(t, err, `unknown tag option "gte" in tag of field CreatedFrom`)
+}
+
+func TestStorageOf_UpdateFields(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+	type User struct {
+		ID     int    `db:"id"`
+		RoleID int    `db:"role_id"`
+		Name   string `db:"name"`
+	}
+
+	ur := sqluct.Table[User](st, "users")
+
+	mock.ExpectExec(`UPDATE users SET name = \$1, role_id = \$2 WHERE users.id = \$3`).
+		WithArgs("John", 0, 123).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, ur.UpdateFields(context.Background(),
+		map[any]any{&ur.R.Name: "John", &ur.R.RoleID: 0}, ur.Eq(&ur.R.ID, 123)))
+
+	require.EqualError(t, ur.UpdateFields(context.Background(), nil),
+		"update: failed to build query: update statements must have at least one Set clause")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageOf_CopyFrom(t *testing.T) {
+	type User struct {
+		ID   int    `db:"id,omitempty"`
+		Name string `db:"name,omitempty"`
+	}
+
+	users := []User{{ID: 1, Name: "John"}, {ID: 2}}
+
+	t.Run("postgres", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		st := sqluct.NewStorage(sqlx.NewDb(db, "postgres"))
+		st.Mapper = &sqluct.Mapper{Dialect: sqluct.DialectPostgres}
+		st.IdentifierQuoter = sqluct.QuoteANSI
+
+		mock.ExpectBegin()
+
+		prep := mock.ExpectPrepare(`COPY "users" \("id", "name"\) FROM STDIN`)
+		prep.ExpectExec().WithArgs(1, "John").WillReturnResult(sqlmock.NewResult(0, 0))
+		prep.ExpectExec().WithArgs(2, "").WillReturnResult(sqlmock.NewResult(0, 0))
+		prep.ExpectExec().WithArgs().WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		ur := sqluct.Table[User](st, "users")
+
+		n, err := ur.CopyFrom(context.Background(), users)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("postgres_comment", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+
+		st := sqluct.NewStorage(sqlx.NewDb(db, "postgres"))
+		st.Mapper = &sqluct.Mapper{Dialect: sqluct.DialectPostgres}
+		st.CommentFromContext = func(ctx context.Context) string { return "route=/users" }
+
+		mock.ExpectBegin()
+
+		prep := mock.ExpectPrepare(`COPY users (id, name) FROM STDIN`)
+		prep.ExpectExec().WithArgs(1, "John").WillReturnResult(sqlmock.NewResult(0, 0))
+		prep.ExpectExec().WithArgs(2, "").WillReturnResult(sqlmock.NewResult(0, 0))
+		prep.ExpectExec().WithArgs().WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		ur := sqluct.Table[User](st, "users")
+
+		n, err := ur.CopyFrom(context.Background(), users)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("insert", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+
+		st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO users \(id,name\) VALUES \(\$1,\$2\),\(\$3,\$4\)`).
+			WithArgs(1, "John", 2, "").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		ur := sqluct.Table[User](st, "users")
+
+		n, err := ur.CopyFrom(context.Background(), users)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("pgx", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+
+		st := sqluct.NewStorage(sqlx.NewDb(db, "pgx"))
+		st.Mapper = &sqluct.Mapper{Dialect: sqluct.DialectPostgres}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO users (id,name) VALUES ($1,$2),($3,$4)`).
+			WithArgs(1, "John", 2, "").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		ur := sqluct.Table[User](st, "users")
+
+		n, err := ur.CopyFrom(context.Background(), users)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("value_exprs", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+
+		st := sqluct.NewStorage(sqlx.NewDb(db, "postgres"))
+		st.Mapper = &sqluct.Mapper{Dialect: sqluct.DialectPostgres}
+		st.DefaultOptions = []func(*sqluct.Options){func(o *sqluct.Options) {
+			o.ValueExprs = map[string]string{"name": "upper(name)"}
+		}}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO users (id,name) VALUES ($1,upper(name)),($2,upper(name))`).
+			WithArgs(1, 2).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		ur := sqluct.Table[User](st, "users")
+
+		n, err := ur.CopyFrom(context.Background(), users)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("postgres_expression", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+
+		type Event struct {
+			At squirrel.Sqlizer `db:"at"`
+		}
+
+		st := sqluct.NewStorage(sqlx.NewDb(db, "postgres"))
+		st.Mapper = &sqluct.Mapper{Dialect: sqluct.DialectPostgres}
+
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`COPY events (at) FROM STDIN`).WillBeClosed()
+		mock.ExpectRollback()
+
+		er := sqluct.Table[Event](st, "events")
+
+		_, err = er.CopyFrom(context.Background(), []Event{{At: squirrel.Expr("now()")}})
+		require.EqualError(t, err, `copy row 0: expression value can not be copied in column "at"`)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("partition", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+
+		st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO users_1 (id,name) VALUES ($1,$2)`).
+			WithArgs(1, "John").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO users_0 (id,name) VALUES ($1,$2)`).
+			WithArgs(2, "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		ur := sqluct.Table[User](st, "users")
+		ur.Partition = func(row User) string {
+			if row.ID%2 == 0 {
+				return "users_0"
+			}
+
+			return "users_1"
+		}
+
+		n, err := ur.CopyFrom(context.Background(), users)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no_columns", func(t *testing.T) {
+		st := sqluct.NewStorage(nil)
+
+		type empty struct {
+			Name string
+		}
+
+		er := sqluct.Table[empty](st, "empty")
+
+		_, err := er.CopyFrom(context.Background(), []empty{{Name: "John"}})
+		require.EqualError(t, err, `missing columns to copy in table "empty"`)
+	})
+}
+
+func TestDeleteReturning(t *testing.T) {
+	type Order struct {
+		ID     int `db:"id"`
+		UserID int `db:"user_id"`
+	}
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	st.Mapper = &sqluct.Mapper{Dialect: sqluct.DialectPostgres}
+	st.IdentifierQuoter = sqluct.QuoteANSI
+	ctx := context.Background()
+
+	mock.ExpectQuery(`DELETE FROM "orders" WHERE user_id = \$1 RETURNING "id", "user_id"`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, 3).AddRow(2, 3))
+
+	rows, err := sqluct.DeleteReturning[Order](ctx, st, st.DeleteStmt("orders").Where(squirrel.Eq{"user_id": 3}))
+	require.NoError(t, err)
+	assert.Equal(t, []Order{{ID: 1, UserID: 3}, {ID: 2, UserID: 3}}, rows)
+
+	mock.ExpectQuery(`DELETE FROM "orders" WHERE user_id = \$1 RETURNING id`).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+	rows, err = sqluct.DeleteReturning[Order](ctx, st, st.DeleteStmt("orders").Where(squirrel.Eq{"user_id": 4}), "id")
+	require.NoError(t, err)
+	assert.Equal(t, []Order{{ID: 5}}, rows)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	st.Mapper.Dialect = sqluct.DialectMySQL
+
+	_, err = sqluct.DeleteReturning[Order](ctx, st, st.DeleteStmt("orders"))
+	require.EqualError(t, err, `RETURNING is not supported for dialect "mysql"`)
+}
+
+func TestStorageOf_Get_notFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+	type User struct {
+		ID int `db:"id"`
+	}
+
+	ur := sqluct.Table[User](st, "users")
+
+	mock.ExpectQuery(`SELECT users.id FROM users WHERE users.id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err = ur.Get(context.Background(), ur.SelectStmt().Where(ur.Eq(&ur.R.ID, 1)))
+	require.EqualError(t, err, "row not found in table users")
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+
+	var nf sqluct.NotFoundError
+
+	require.True(t, errors.As(err, &nf))
+	assert.Equal(t, "users", nf.Table)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageOf_SelectFields(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	st.IdentifierQuoter = sqluct.QuoteANSI
+
+	type User struct {
+		ID     int    `db:"id"`
+		RoleID int    `db:"role_id"`
+		Name   string `db:"name"`
+	}
+
+	type UserName struct {
+		Name string `db:"name"`
+	}
+
+	ur := sqluct.Table[User](st, "users")
+	ctx := context.Background()
+
+	mock.ExpectQuery(`SELECT "users"."id", "users"."name" FROM "users" WHERE "users"."role_id" = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "John"))
+
+	users, err := ur.List(ctx, ur.SelectFields(&ur.R.ID, &ur.R.Name).Where(ur.Eq(&ur.R.RoleID, 2)))
+	require.NoError(t, err)
+	assert.Equal(t, []User{{ID: 1, Name: "John"}}, users)
+
+	mock.ExpectQuery(`SELECT "users"."name" FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("John"))
+
+	names, err := sqluct.List[UserName](ctx, st, ur.SelectFields(&ur.R.Name))
+	require.NoError(t, err)
+	assert.Equal(t, []UserName{{Name: "John"}}, names)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageOf_ListPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+	type User struct {
+		ID int `db:"id"`
+	}
+
+	ur := sqluct.Table[User](st, "users")
+	ctx := context.Background()
+
+	mock.ExpectQuery(`SELECT users.id FROM users LIMIT 100 OFFSET 200`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(201))
+
+	users, page, err := ur.ListPage(ctx, ur.SelectStmt(), 3, 1000000, 100)
+	require.NoError(t, err)
+	assert.Equal(t, []User{{ID: 201}}, users)
+	assert.Equal(t, sqluct.Page{Number: 3, Limit: 100, Offset: 200}, page)
+
+	mock.ExpectQuery(`SELECT users.id FROM users LIMIT 10$`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	_, page, err = ur.ListPage(ctx, ur.SelectStmt(), 1, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, sqluct.Page{Number: 1, Limit: 10}, page)
+
+	mock.ExpectQuery(`SELECT users.id FROM users LIMIT 10 OFFSET 10`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+
+	users, page, err = ur.ListPage(ctx, ur.SelectStmt(), 2, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []User{{ID: 11}}, users)
+	assert.Equal(t, sqluct.Page{Number: 2, Limit: 10, Offset: 10}, page)
+
+	_, _, err = ur.ListPage(ctx, ur.SelectStmt(), 0, 10, 10)
+	require.ErrorIs(t, err, sqluct.ErrInvalidPage)
+
+	_, _, err = ur.ListPage(ctx, ur.SelectStmt(), 2, 0, 0)
+	require.ErrorIs(t, err, sqluct.ErrInvalidPerPage)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageOf_UpdateRow(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+	type User struct {
+		ID   int    `db:"id,omitempty"`
+		Name string `db:"name"`
+	}
+
+	ur := sqluct.Table[User](st, "users")
+	ctx := context.Background()
+
+	mock.ExpectExec(`UPDATE users SET name = \$1 WHERE users.id = \$2`).
+		WithArgs("John", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	n, err := ur.UpdateRow(ctx, User{Name: "John"}, ur.Eq(&ur.R.ID, 1))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	mock.ExpectExec(`DELETE FROM users WHERE users.id = \$1 AND users.name = \$2`).
+		WithArgs(2, "Jane").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	n, err = ur.DeleteWhere(ctx, ur.Eq(&ur.R.ID, 2), ur.Eq(&ur.R.Name, "Jane"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	mock.ExpectExec(`UPDATE users SET id = id \+ \$1 WHERE users.name = \$2`).
+		WithArgs(-1, "Jane").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	n, err = ur.Increment(ctx, &ur.R.ID, -1, ur.Eq(&ur.R.Name, "Jane"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	_, err = ur.UpdateRow(ctx, User{Name: "John"}, nil)
+	require.EqualError(t, err, "update: missing condition")
+
+	_, err = ur.Increment(ctx, &ur.R.ID, 1)
+	require.EqualError(t, err, "increment: missing condition")
+
+	_, err = ur.DeleteWhere(ctx)
+	require.EqualError(t, err, "delete: missing condition")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageOf_CountDistinct(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+	type Order struct {
+		ID     int `db:"id"`
+		UserID int `db:"user_id"`
+	}
+
+	or := sqluct.Table[Order](st, "orders")
+	ctx := context.Background()
+	qb := or.SelectStmt().Where(squirrel.Gt{"id": 10}).OrderBy("id").Limit(10).Offset(20)
+
+	mock.ExpectQuery(`SELECT COUNT(DISTINCT t.c) FROM (SELECT orders.user_id AS c FROM orders WHERE id > $1 ORDER BY id) AS t`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	cnt, err := or.CountDistinct(ctx, qb, &or.R.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), cnt)
+
+	mock.ExpectQuery(`SELECT COUNT(*) FROM (SELECT 1 FROM orders WHERE id > $1 ORDER BY id) AS t`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+
+	cnt, err = or.Count(ctx, qb)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), cnt)
+
+	mock.ExpectQuery(`SELECT COUNT(*) FROM (SELECT 1 FROM orders WHERE id > $1 ORDER BY id FOR UPDATE) AS t`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+
+	cnt, err = or.Count(ctx, qb.Suffix("FOR UPDATE"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), cnt)
+
+	_, err = or.CountDistinct(ctx, qb, &Order{})
+	require.EqualError(t, err, "failed to resolve distinct column: unknown field or row or not a pointer")
+
+	rf := st.MakeReferencer()
+	o := &Order{}
+
+	mock.ExpectQuery(`SELECT COUNT(DISTINCT t.c) FROM (SELECT o.user_id AS c FROM orders AS o) AS t`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	cnt, err = st.CountDistinct(ctx, rf.From(st.QueryBuilder().Select(), o, "orders", "o"), rf, &o.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), cnt)
+
+	mock.ExpectQuery(`SELECT COUNT(DISTINCT t.c) FROM (SELECT user_id AS c FROM orders) AS t`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	cnt, err = st.CountDistinct(ctx, st.QueryBuilder().Select().From("orders"), nil, st.Q("user_id"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), cnt)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAlias(t *testing.T) {
+	type User struct {
+		ID        int `db:"id"`
+		ManagerID int `db:"manager_id"`
+	}
+
+	rf := &sqluct.Referencer{}
+	manager := sqluct.Alias[User](rf, "manager")
+	employee := sqluct.Alias[User](rf, "employee")
+
+	assert.Equal(t, "employee.manager_id = manager.id", rf.Fmt("%s = %s", &employee.ManagerID, &manager.ID))
+	assert.PanicsWithValue(t, "non-nil Referencer expected", func() {
+		sqluct.Alias[User](nil, "u")
+	})
+}
+
+func TestProject(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+	type UserName struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	ctx := context.Background()
+	names := sqluct.Project[UserName](st)
+
+	assert.Equal(t, []string{"id", "name"}, names.Columns())
+
+	mock.ExpectQuery("SELECT id, name FROM users WHERE active = $1").WithArgs(true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+
+	rows, err := names.List(ctx, names.SelectStmt("users").Where(squirrel.Eq{"active": true}))
+	require.NoError(t, err)
+	assert.Equal(t, []UserName{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}, rows)
+
+	mock.ExpectQuery("SELECT id, name FROM users WHERE id = $1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+
+	row, err := names.Get(ctx, names.Select(st.QueryBuilder().Select()).From("users").Where(squirrel.Eq{"id": 1}))
+	require.NoError(t, err)
+	assert.Equal(t, UserName{ID: 1, Name: "a"}, row)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, []string{"name"}, sqluct.Project[UserName](st, sqluct.Columns("name")).Columns())
+}
+
+func TestStorageOf_InsertRowsN(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+	type Tag struct {
+		Name string `db:"name"`
+	}
+
+	tr := sqluct.Table[Tag](st, "tags")
+	ctx := context.Background()
+	rows := []Tag{{Name: "a"}, {Name: "b"}}
+
+	mock.ExpectExec("INSERT INTO tags (name) VALUES ($1),($2)").
+		WithArgs("a", "b").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	n, err := tr.InsertRowsN(ctx, rows)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	mock.ExpectExec("INSERT INTO tags (name) VALUES ($1),($2)").
+		WithArgs("a", "b").WillReturnResult(sqlmock.NewResult(0, -1))
+
+	n, err = tr.InsertRowsN(ctx, rows)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	mock.ExpectExec("INSERT INTO tags (name) VALUES ($1),($2)").
+		WithArgs("a", "b").WillReturnResult(sqlmock.NewErrorResult(errors.New("not supported")))
+
+	n, err = tr.InsertRowsN(ctx, rows)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	mock.ExpectExec("INSERT INTO tags (name) VALUES ($1),($2)").
+		WithArgs("a", "b").WillReturnError(errors.New("failed"))
+
+	_, err = tr.InsertRowsN(ctx, rows)
+	require.EqualError(t, err, "insert: failed")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageOf_Partition(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+	type Event struct {
+		Name string    `db:"name"`
+		At   time.Time `db:"at"`
+	}
+
+	er := sqluct.Table[Event](st, "events")
+	er.Partition = func(row Event) string {
+		return "events_" + row.At.Format("2006_01")
+	}
+
+	ctx := context.Background()
+	jan := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec("INSERT INTO events_2024_01 (name,at) VALUES ($1,$2)").
+		WithArgs("a", jan).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err = er.InsertRow(ctx, Event{Name: "a", At: jan})
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO events_2024_02 (name,at) VALUES ($1,$2),($3,$4)").
+		WithArgs("b", feb, "d", feb).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO events_2024_01 (name,at) VALUES ($1,$2)").
+		WithArgs("c", jan).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	res, err := er.InsertRows(ctx, []Event{{Name: "b", At: feb}, {Name: "c", At: jan}, {Name: "d", At: feb}})
+	require.NoError(t, err)
+
+	n, err := res.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageOf_compositeKey(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	type UserRole struct {
+		UserID int    `db:"user_id,pk"`
+		RoleID int    `db:"role_id,pk"`
+		Note   string `db:"note"`
+	}
+
+	type UserRoleKey struct {
+		RoleID int `db:"role_id"`
+		UserID int `db:"user_id"`
+	}
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	st.IdentifierQuoter = sqluct.QuoteANSI
+
+	s := sqluct.Table[UserRole](st, "user_roles")
+	ctx := context.Background()
+
+	assertStatementArgs(t, `SELECT "user_roles"."user_id", "user_roles"."role_id", "user_roles"."note" `+
+		`FROM "user_roles" WHERE ("user_roles"."user_id" = $1 AND "user_roles"."role_id" = $2)`,
+		[]interface{}{1, 2}, s.SelectStmt().Where(s.WhereKey(UserRoleKey{UserID: 1, RoleID: 2})))
+
+	_, _, err = s.SelectStmt().Where(s.WhereKey(1)).ToSql()
+	assert.EqualError(t, err, `invalid key: 2 values expected for table "user_roles", 1 received`)
+
+	_, _, err = s.SelectStmt().Where(s.WhereKey(struct {
+		UserID int `db:"user_id"`
+	}{})).ToSql()
+	assert.EqualError(t, err, `invalid key: missing column "role_id" in struct { UserID int "db:\"user_id\"" }`)
+
+	mock.ExpectQuery(`SELECT "user_roles"."user_id", "user_roles"."role_id", "user_roles"."note" `+
+		`FROM "user_roles" WHERE ("user_roles"."user_id" = $1 AND "user_roles"."role_id" = $2)`).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role_id", "note"}).AddRow(1, 2, "admin"))
+
+	row, err := s.GetByID(ctx, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, UserRole{UserID: 1, RoleID: 2, Note: "admin"}, row)
+
+	mock.ExpectExec(`UPDATE "user_roles" SET "user_id" = $1, "role_id" = $2, "note" = $3 `+
+		`WHERE ("user_roles"."user_id" = $4 AND "user_roles"."role_id" = $5)`).
+		WithArgs(1, 2, "owner", 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	affected, err := s.UpdateByID(ctx, UserRole{UserID: 1, RoleID: 2, Note: "owner"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	mock.ExpectExec(`DELETE FROM "user_roles" WHERE ("user_roles"."user_id" = $1 AND "user_roles"."role_id" = $2)`).
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	affected, err = s.DeleteByID(ctx, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	ns := sqluct.Table[struct {
+		Name string `db:"name"`
+	}](st, "names")
+
+	_, err = ns.DeleteByID(ctx, 1)
+	assert.EqualError(t, err, `delete: failed to build query: missing primary key in table "names"`)
+}
+
+func TestStorageOf_DeleteByIDs(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	type UserRole struct {
+		UserID int `db:"user_id,pk"`
+		RoleID int `db:"role_id,pk"`
+	}
+
+	type User struct {
+		ID   int    `db:"id,serialIdentity"`
+		Name string `db:"name"`
+	}
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	ur := sqluct.Table[UserRole](st, "user_roles")
+	us := sqluct.Table[User](st, "users")
+	ctx := context.Background()
+
+	affected, err := us.DeleteByIDs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), affected)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM users WHERE users.id IN ($1,$2)").
+		WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	affected, err = us.DeleteByIDs(ctx, 1, User{ID: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), affected)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM user_roles WHERE ((user_roles.user_id = $1 AND user_roles.role_id = $2) OR "+
+		"(user_roles.user_id = $3 AND user_roles.role_id = $4))").
+		WithArgs(1, 2, 3, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	affected, err = ur.DeleteByIDs(ctx, UserRole{UserID: 1, RoleID: 2}, UserRole{UserID: 3, RoleID: 4})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	ids := make([]interface{}, 40000)
+	for i := range ids {
+		ids[i] = i
+	}
+
+	first, _ := us.DeleteStmt().Where(squirrel.Eq{"users.id": ids[:32766]}).MustSql()
+	second, _ := us.DeleteStmt().Where(squirrel.Eq{"users.id": ids[32766:]}).MustSql()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(first).WillReturnResult(sqlmock.NewResult(0, 32766))
+	mock.ExpectExec(second).WillReturnResult(sqlmock.NewResult(0, 7234))
+	mock.ExpectCommit()
+
+	affected, err = us.DeleteByIDs(ctx, ids...)
+	require.NoError(t, err)
+	assert.Equal(t, int64(40000), affected)
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	_, err = ur.DeleteByIDs(ctx, 1)
+	assert.EqualError(t, err, `delete ids 0-0: failed to build query: invalid key: 2 values expected for table "user_roles", 1 received`)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageOf_WithTx(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	type User struct {
+		ID   int    `db:"id,serialIdentity"`
+		Name string `db:"name"`
+	}
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	st.Mapper = &sqluct.Mapper{Dialect: sqluct.DialectPostgres}
+
+	users := sqluct.Table[User](st, "users")
+	ctx := context.Background()
+
+	noTx := users.WithTx(ctx)
+	_, err = noTx.List(ctx, users.SelectStmt())
+	require.EqualError(t, err, `no running transaction for table "users"`)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO users (id,name) VALUES ($1,$2) RETURNING id").
+		WithArgs(0, "John").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("SELECT users.id, users.name FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "John"))
+	mock.ExpectCommit()
+
+	require.NoError(t, st.InTx(ctx, func(txCtx context.Context) error {
+		txUsers := users.WithTx(txCtx)
+
+		// Statements use pinned transaction even with context without transaction.
+		id, err := txUsers.InsertRow(ctx, User{Name: "John"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), id)
+
+		list, err := txUsers.List(ctx, txUsers.SelectStmt())
+		require.NoError(t, err)
+		assert.Equal(t, []User{{ID: 1, Name: "John"}}, list)
+
+		return nil
+	}))
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteReturning_replica(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	rdb, rmock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	type row struct {
+		ID int `db:"id"`
+	}
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	st.Replica = sqlx.NewDb(rdb, "mock")
+
+	mock.ExpectQuery("DELETE FROM rows WHERE id = $1 RETURNING id").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	deleted, err := sqluct.DeleteReturning[row](context.Background(), st,
+		st.DeleteStmt("rows").Where(squirrel.Eq{"id": 1}))
+	require.NoError(t, err)
+	assert.Equal(t, []row{{ID: 1}}, deleted)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.NoError(t, rmock.ExpectationsWereMet())
+}
+
+func TestStorageOf_InsertRow_returning(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	type row struct {
+		ID   int    `db:"id,serialIdentity"`
+		Name string `db:"name"`
+	}
+
+	var traced []string
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	st.Mapper = &sqluct.Mapper{Dialect: sqluct.DialectMariaDB}
+	st.Format = squirrel.Question
+	st.CommentFromContext = func(ctx context.Context) string { return "insert" }
+	st.Trace = func(ctx context.Context, stmt string, args []interface{}) (context.Context, func(error)) {
+		traced = append(traced, stmt)
+
+		return ctx, func(error) {}
+	}
+
+	rows := sqluct.Table[row](st, "rows")
+
+	mock.ExpectQuery("/*insert*/ INSERT INTO rows (id,name) VALUES (?,?) RETURNING id").WithArgs(0, "John").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	id, err := rows.InsertRow(context.Background(), row{Name: "John"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+	assert.Equal(t, []string{"/*insert*/ INSERT INTO rows (id,name) VALUES (?,?) RETURNING id"}, traced)
+
+	mock.ExpectQuery("/*insert*/ INSERT INTO rows (id,name) VALUES (?,?) RETURNING id").WithArgs(0, "Jane").
+		WillReturnError(errors.New("duplicate"))
+
+	_, err = rows.InsertRow(context.Background(), row{Name: "Jane"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "duplicate")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageOf_InsertRow_replica(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	rdb, rmock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	type row struct {
+		ID   int    `db:"id,serialIdentity"`
+		Name string `db:"name"`
+	}
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	st.Mapper = &sqluct.Mapper{Dialect: sqluct.DialectPostgres}
+	st.Replica = sqlx.NewDb(rdb, "mock")
+
+	mock.ExpectQuery("INSERT INTO rows (id,name) VALUES ($1,$2) RETURNING id").WithArgs(0, "John").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	rows := sqluct.Table[row](st, "rows")
+
+	id, err := rows.InsertRow(context.Background(), row{Name: "John"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.NoError(t, rmock.ExpectationsWereMet())
+}
+
+func TestDialectMariaDB(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	type User struct {
+		ID   int    `db:"id,serialIdentity"`
+		Name string `db:"name"`
+	}
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	st.Mapper = &sqluct.Mapper{Dialect: sqluct.DialectMariaDB}
+	st.Format = squirrel.Question
+	st.IdentifierQuoter = sqluct.QuoteBackticks
+
+	us := sqluct.Table[User](st, "users")
+	ctx := context.Background()
+
+	mock.ExpectQuery("INSERT INTO `users` (`id`,`name`) VALUES (?,?) RETURNING id").
+		WithArgs(0, "John").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+
+	id, err := us.InsertRow(ctx, User{Name: "John"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), id)
+
+	mock.ExpectQuery("DELETE FROM `users` WHERE `users`.`id` = ? RETURNING `id`, `name`").
+		WithArgs(12).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(12, "John"))
+
+	deleted, err := sqluct.DeleteReturning[User](ctx, st, us.DeleteStmt().Where(us.Eq(&us.R.ID, 12)))
+	require.NoError(t, err)
+	assert.Equal(t, []User{{ID: 12, Name: "John"}}, deleted)
+
+	assertStatementArgs(t, "INSERT IGNORE INTO `users` (`id`,`name`) VALUES (?,?)", []interface{}{0, "John"},
+		st.InsertStmt("users", User{Name: "John"}, sqluct.InsertIgnore))
+	assert.PanicsWithValue(t, `can not apply ON CONFLICT for dialect "mariadb"`, func() {
+		st.InsertStmt("users", User{}, us.OnConflict([]interface{}{&us.R.ID}, nil))
+	})
+
+	mock.ExpectQuery("ANALYZE SELECT id FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "select_type", "Extra"}).AddRow(1, "SIMPLE", "Using index"))
+
+	plan, err := st.Explain(ctx, st.QueryBuilder().Select("id").From("users"), true)
+	require.NoError(t, err)
+	assert.Equal(t, "Using index", plan)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
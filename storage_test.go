@@ -2,13 +2,17 @@ package sqluct_test
 
 import (
 	"context"
+	"database/sql"
 	"database/sql/driver"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	"github.com/Masterminds/squirrel"
+	"github.com/bool64/ctxd"
 	"github.com/bool64/sqluct"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
@@ -395,6 +399,48 @@ func TestStorage_SelectStmt_backticks(t *testing.T) {
 	assert.Equal(t, "SELECT `order_id`, `amount` FROM `table`", query)
 }
 
+func TestStorage_SelectStmt_qualifyColumns(t *testing.T) {
+	type Row struct {
+		OrderID int `db:"order_id"`
+		Amount  int `db:"amount"`
+	}
+
+	st := sqluct.NewStorage(nil)
+
+	assertStatementArgs(t, "SELECT orders.order_id, orders.amount FROM orders", nil,
+		st.SelectStmt("orders", Row{}, sqluct.QualifyColumns))
+
+	st.IdentifierQuoter = sqluct.QuoteANSI
+
+	// Generated columns are bare by default.
+	assertStatementArgs(t, `SELECT "order_id", "amount" FROM "orders"`, nil,
+		st.SelectStmt("orders", Row{}))
+
+	assertStatementArgs(t, `SELECT "orders"."order_id", "orders"."amount" FROM "orders"`, nil,
+		st.SelectStmt("orders", Row{}, sqluct.QualifyColumns))
+
+	// Table name is quoted as a single identifier.
+	assertStatementArgs(t, `SELECT "public.orders"."order_id" FROM "public.orders"`, nil,
+		st.SelectStmt("public.orders", Row{}, sqluct.QualifyColumns, sqluct.Columns("order_id")))
+
+	// Referencer prefix takes precedence, both parts are quoted same way.
+	rf := st.MakeReferencer()
+	o := &Row{}
+	rf.AddTableAlias(o, "o")
+
+	assertStatementArgs(t, `SELECT "o"."order_id", "o"."amount" FROM "orders"`, nil,
+		st.SelectStmt("orders", o, sqluct.QualifyColumns, rf.ColumnsOf(o)))
+
+	st.DefaultOptions = []func(*sqluct.Options){sqluct.QualifyColumns}
+
+	assertStatementArgs(t, `SELECT "orders"."order_id", "orders"."amount" FROM "orders"`, nil,
+		st.SelectStmt("orders", Row{}))
+
+	// Columns of INSERT and UPDATE are not affected.
+	assertStatementArgs(t, `INSERT INTO "orders" ("order_id","amount") VALUES ($1,$2)`, []interface{}{1, 2},
+		st.InsertStmt("orders", Row{OrderID: 1, Amount: 2}))
+}
+
 func TestStorage_DB(t *testing.T) {
 	db, _, err := sqlmock.New()
 	require.NoError(t, err)
@@ -405,6 +451,37 @@ func TestStorage_DB(t *testing.T) {
 	assert.Equal(t, dbx, st.DB())
 }
 
+func TestNewStorageFromDB(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	st := sqluct.NewStorageFromDB(db, "postgres")
+
+	assert.Equal(t, db, st.DB().DB)
+	assert.Equal(t, "postgres", st.DB().DriverName())
+
+	mock.ExpectQuery("SELECT id FROM users WHERE name = $1").WithArgs("a").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	var id int
+
+	require.NoError(t, st.Select(context.Background(),
+		st.QueryBuilder().Select("id").From("users").Where(squirrel.Eq{"name": "a"}), &id))
+	assert.Equal(t, 1, id)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNewStorageFromConnector(t *testing.T) {
+	st := sqluct.NewStorageFromConnector(dumpConnector{}, "postgres")
+
+	assert.Equal(t, "postgres", st.DB().DriverName())
+
+	// Statement reaches the connector, dumpStmt fails execution with "skip".
+	_, err := st.Exec(context.Background(), st.DeleteStmt("users").Where(squirrel.Eq{"id": 1}))
+	require.EqualError(t, err, "skip")
+	require.NoError(t, st.DB().Close())
+}
+
 func TestStmt_ToSql(t *testing.T) {
 	s, a, err := sqluct.Stmt("SELECT * FROM foo WHERE id=? AND name=?", 1, "bar").ToSql()
 	assert.Equal(t, "SELECT * FROM foo WHERE id=? AND name=?", s)
@@ -418,3 +495,1135 @@ func TestPlain_ToSql(t *testing.T) {
 	assert.Nil(t, a)
 	require.NoError(t, err)
 }
+
+func TestStorage_SelectStmt_extraColumns(t *testing.T) {
+	st := sqluct.NewStorage(nil)
+	st.IdentifierQuoter = sqluct.QuoteANSI
+
+	type Row struct {
+		ID        int       `db:"id"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+
+	rf := st.MakeReferencer()
+	r := &Row{}
+	rf.AddTableAlias(r, "rows")
+
+	assertStatement(t, `SELECT "id", "created_at", now() AS server_time FROM "rows"`,
+		st.SelectStmt("rows", r, sqluct.ExtraColumns("now() AS server_time")))
+	assertStatement(t, `SELECT "rows"."id", "rows"."created_at", now() - "rows"."created_at" AS age, 1 AS one FROM "rows"`,
+		st.SelectStmt("rows", r, rf.ColumnsOf(r),
+			sqluct.ExtraColumns(rf.Fmt("now() - %s AS age", &r.CreatedAt)),
+			sqluct.ExtraColumns("1 AS one"),
+		))
+}
+
+func TestStorage_ExecRaw(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	ctx := context.Background()
+
+	var traced []string
+
+	st.Trace = func(ctx context.Context, stmt string, args []interface{}) (context.Context, func(error)) {
+		traced = append(traced, stmt)
+
+		return ctx, func(error) {}
+	}
+
+	mock.ExpectExec("CREATE INDEX users_name ON users (name)").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err = st.ExecRaw(ctx, "CREATE INDEX users_name ON users (name)")
+	require.NoError(t, err)
+
+	mock.ExpectQuery("SELECT id FROM users WHERE name = $1").WithArgs("John").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	var ids []int
+
+	require.NoError(t, st.SelectRaw(ctx, &ids, "SELECT id FROM users WHERE name = $1", "John"))
+	assert.Equal(t, []int{1, 2}, ids)
+	assert.Equal(t, []string{"CREATE INDEX users_name ON users (name)", "SELECT id FROM users WHERE name = $1"}, traced)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_ExecMany(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TABLE foo \(id INT\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO foo VALUES \(1\)`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, st.ExecMany(context.Background(), "CREATE TABLE foo (id INT);\nINSERT INTO foo VALUES (1);\n"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_ExecMany_error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TABLE foo \(id INT\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO bar VALUES \(1\)`).WillReturnError(errors.New("no table"))
+	mock.ExpectRollback()
+
+	err = st.ExecMany(context.Background(), "CREATE TABLE foo (id INT);\nINSERT INTO bar VALUES (1);\nINSERT INTO foo VALUES (1);")
+	require.EqualError(t, err, "failed to execute statement #1: no table")
+
+	var se ctxd.StructuredError
+
+	require.True(t, errors.As(err, &se))
+	assert.Equal(t, "INSERT INTO bar VALUES (1)", se.Fields()["statement"])
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_InTx_directExec(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+	mock.ExpectExec(`DELETE FROM users WHERE id = \$1`).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM users WHERE id = \$1`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT id FROM users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectRollback()
+
+	ctx := context.Background()
+
+	_, err = st.DeleteStmt("users").Where(squirrel.Eq{"id": 1}).ExecContext(ctx)
+	require.NoError(t, err)
+
+	require.EqualError(t, st.InTx(ctx, func(ctx context.Context) error {
+		if _, err := st.DeleteStmt("users").Where(squirrel.Eq{"id": 2}).ExecContext(ctx); err != nil {
+			return err
+		}
+
+		var id int
+
+		if err := st.QueryBuilder().Select("id").From("users").QueryRowContext(ctx).Scan(&id); err != nil {
+			return err
+		}
+
+		assert.Equal(t, 3, id)
+
+		return errors.New("failed")
+	}), "failed")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_ExecExpect(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	ctx := context.Background()
+	q := st.UpdateStmt("users", nil).Set("name", "John").Where(squirrel.Eq{"id": 1})
+
+	mock.ExpectExec(`UPDATE users SET name = \$1 WHERE id = \$2`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE users SET name = \$1 WHERE id = \$2`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE users SET name = \$1 WHERE id = \$2`).WillReturnError(errors.New("failed"))
+
+	require.NoError(t, st.ExecExpect(ctx, q, 1))
+
+	err = st.ExecExpect(ctx, q, 1)
+	require.EqualError(t, err, "unexpected rows affected: got 0, want 1")
+
+	var ue sqluct.ErrUnexpectedRowsAffected
+
+	require.True(t, errors.As(err, &ue))
+	assert.Equal(t, int64(0), ue.Got)
+	assert.Equal(t, int64(1), ue.Want)
+
+	require.EqualError(t, st.ExecExpect(ctx, q, 1), "failed")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_Select_inline(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	st.IdentifierQuoter = sqluct.QuoteANSI
+
+	type Address struct {
+		Street string `db:"street"`
+		City   string `db:"city"`
+	}
+
+	type Row struct {
+		ID   int     `db:"id"`
+		Addr Address `db:"addr,inline"`
+	}
+
+	mock.ExpectQuery(`SELECT "id", "addr_street" AS "addr.street", "addr_city" AS "addr.city" FROM "rows"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "addr.street", "addr.city"}).AddRow(1, "Main", "Berlin"))
+
+	var rows []Row
+
+	require.NoError(t, st.Select(context.Background(), st.SelectStmt("rows", Row{}), &rows))
+	assert.Equal(t, []Row{{ID: 1, Addr: Address{Street: "Main", City: "Berlin"}}}, rows)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_InTx_hooks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	var (
+		calls  []string
+		errs   []error
+		ctx    = context.Background()
+		record = func(name string, err error) func(ctx context.Context) error {
+			return func(ctx context.Context) error {
+				assert.Nil(t, sqluct.TxFromContext(ctx))
+				calls = append(calls, name)
+
+				return err
+			}
+		}
+	)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	st.OnError = func(_ context.Context, err error) {
+		errs = append(errs, err)
+	}
+
+	require.EqualError(t, sqluct.OnCommit(ctx, record("c0", nil)), "no running transaction")
+	require.EqualError(t, sqluct.OnRollback(ctx, record("r0", nil)), "no running transaction")
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	require.EqualError(t, st.InTx(ctx, func(ctx context.Context) error {
+		require.NoError(t, sqluct.OnCommit(ctx, record("c1", nil)))
+		require.NoError(t, sqluct.OnRollback(ctx, record("r1", nil)))
+
+		return st.InTx(ctx, func(ctx context.Context) error {
+			require.NoError(t, sqluct.OnCommit(ctx, record("c2", errors.New("failed"))))
+			require.NoError(t, sqluct.OnCommit(ctx, record("c3", nil)))
+
+			return nil
+		})
+	}), "commit hook #1 failed: failed")
+	assert.Equal(t, []string{"c1", "c2", "c3"}, calls)
+	require.Len(t, errs, 1)
+
+	calls = nil
+	errs = nil
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	require.EqualError(t, st.InTx(ctx, func(ctx context.Context) error {
+		require.NoError(t, sqluct.OnCommit(ctx, record("c1", nil)))
+		require.NoError(t, sqluct.OnRollback(ctx, record("r1", errors.New("failed"))))
+		require.NoError(t, sqluct.OnRollback(ctx, record("r2", nil)))
+
+		return errors.New("tx failed")
+	}), "tx failed")
+	assert.Equal(t, []string{"r1", "r2"}, calls)
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "rollback hook #0 failed: failed")
+
+	calls = nil
+
+	mock.ExpectBegin()
+	mock.ExpectCommit().WillReturnError(errors.New("conflict"))
+
+	require.EqualError(t, st.InTx(ctx, func(ctx context.Context) error {
+		require.NoError(t, sqluct.OnCommit(ctx, record("c1", nil)))
+		require.NoError(t, sqluct.OnRollback(ctx, record("r1", nil)))
+
+		return nil
+	}), "failed to commit: conflict")
+	assert.Equal(t, []string{"r1"}, calls)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_InsertSelectStmt(t *testing.T) {
+	st := sqluct.NewStorage(nil)
+	st.IdentifierQuoter = sqluct.QuoteANSI
+
+	type Row struct {
+		ID        int       `db:"id,readonly"`
+		Name      string    `db:"name,omitempty"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	assertStatementArgs(t, `INSERT INTO "archive" ("name","created_at") `+
+		`SELECT "name", "created_at" FROM "rows" WHERE "created_at" < $1`, []interface{}{ts},
+		st.InsertSelectStmt("archive", Row{},
+			st.SelectStmt("rows", Row{}, sqluct.Columns("name", "created_at")).Where(squirrel.Lt{`"created_at"`: ts})))
+
+	assertStatement(t, `INSERT INTO "archive" ("name") SELECT "name" FROM "rows"`,
+		st.InsertSelectStmt("archive", Row{}, st.SelectStmt("rows", Row{}, sqluct.Columns("name")), sqluct.Columns("name")))
+}
+
+func TestStorage_DefaultOptions(t *testing.T) {
+	st := sqluct.NewStorage(nil)
+	st.IdentifierQuoter = sqluct.QuoteANSI
+	st.DefaultOptions = []func(*sqluct.Options){
+		sqluct.SkipZeroValues,
+		func(o *sqluct.Options) {
+			o.PrepareColumn = func(col string) string { return "t." + col }
+		},
+	}
+
+	type Row struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	assertStatementArgs(t, `UPDATE "rows" SET t.name = $1`, []interface{}{"John"},
+		st.UpdateStmt("rows", Row{Name: "John"}))
+	assertStatement(t, `SELECT t.id, t.name FROM "rows"`, st.SelectStmt("rows", Row{}))
+	assertStatementArgs(t, `SELECT id FROM "rows" WHERE t.id = $1`, []interface{}{1},
+		st.QueryBuilder().Select("id").From(`"rows"`).Where(st.WhereEq(Row{ID: 1})))
+	assertStatementArgs(t, `INSERT INTO "rows" ("name") VALUES ($1)`, []interface{}{"John"},
+		st.InsertStmt("rows", Row{Name: "John"}, func(o *sqluct.Options) {
+			o.PrepareColumn = nil
+		}))
+}
+
+func TestStorage_Expr(t *testing.T) {
+	st := sqluct.NewStorage(nil)
+	st.IdentifierQuoter = sqluct.QuoteANSI
+
+	assertStatementArgs(t, `SELECT "id" FROM "orders" WHERE "order" > $1 AND "orders"."group" = $2`, []interface{}{0, "a"},
+		st.SelectStmt("orders", struct {
+			ID int `db:"id"`
+		}{}).Where(st.Expr("%s > ? AND %s = ?", st.Q("order"), 0, st.Q("orders", "group"), "a")))
+}
+
+func TestStorage_InTx_panic(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM users").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+
+	assert.PanicsWithValue(t, "failed", func() {
+		_ = st.InTx(context.Background(), func(ctx context.Context) error {
+			_, err := st.Exec(ctx, sqluct.Plain("DELETE FROM users"))
+			require.NoError(t, err)
+
+			return st.InTx(ctx, func(ctx context.Context) error {
+				panic("failed")
+			})
+		})
+	})
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_SelectMulti(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM users WHERE id = \$1`).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`SELECT name FROM roles`).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("admin").AddRow("user"))
+	mock.ExpectCommit()
+
+	var (
+		id    int
+		roles []string
+	)
+
+	require.NoError(t, st.SelectMulti(context.Background(),
+		[]sqluct.ToSQL{
+			st.QueryBuilder().Select("id").From("users").Where(squirrel.Eq{"id": 1}),
+			sqluct.Plain("SELECT name FROM roles"),
+		},
+		[]interface{}{&id, &roles},
+	))
+	assert.Equal(t, 1, id)
+	assert.Equal(t, []string{"admin", "user"}, roles)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_SelectMulti_batch(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	st.Mapper = &sqluct.Mapper{Dialect: sqluct.DialectPostgres}
+	st.MultiStatements = true
+
+	type User struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	mock.ExpectQuery(`SELECT id, name FROM users WHERE id IN ($1,$2); `+
+		`SELECT COUNT(*) FROM roles WHERE name <> '$1' AND user_id = $3; SELECT name FROM users WHERE id = $4`).
+		WithArgs(1, 2, 3, 4).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "John").AddRow(2, "Jane"),
+			sqlmock.NewRows([]string{"count"}).AddRow(5),
+			sqlmock.NewRows([]string{"name"}).AddRow("Jack").AddRow("ignored"),
+		)
+
+	var (
+		users []User
+		cnt   int
+		name  string
+	)
+
+	qb := st.QueryBuilder()
+
+	require.NoError(t, st.SelectMulti(context.Background(),
+		[]sqluct.ToSQL{
+			qb.Select("id", "name").From("users").Where(squirrel.Eq{"id": []int{1, 2}}),
+			qb.Select("COUNT(*)").From("roles").Where("name <> '$1'").Where(squirrel.Eq{"user_id": 3}),
+			qb.Select("name").From("users").Where(squirrel.Eq{"id": 4}),
+		},
+		[]interface{}{&users, &cnt, &name},
+	))
+	assert.Equal(t, []User{{ID: 1, Name: "John"}, {ID: 2, Name: "Jane"}}, users)
+	assert.Equal(t, 5, cnt)
+	assert.Equal(t, "Jack", name)
+
+	mock.ExpectQuery(`SELECT id, name FROM users; SELECT id FROM roles`).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "John"),
+			sqlmock.NewRows([]string{"id"}),
+		)
+
+	var (
+		user User
+		id   int
+	)
+
+	err = st.SelectMulti(context.Background(),
+		[]sqluct.ToSQL{sqluct.Plain("SELECT id, name FROM users"), sqluct.Plain("SELECT id FROM roles")},
+		[]interface{}{&user, &id},
+	)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	assert.Equal(t, "failed to select query #1: sql: no rows in result set", err.Error())
+
+	mock.ExpectQuery(`SELECT id, name FROM users; SELECT id FROM roles`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).AddRow(1, "John", "a@b.c"))
+
+	err = st.SelectMulti(context.Background(),
+		[]sqluct.ToSQL{sqluct.Plain("SELECT id, name FROM users"), sqluct.Plain("SELECT id FROM roles")},
+		[]interface{}{&user, &id},
+	)
+	require.EqualError(t, err, `failed to select query #0: missing destination field for column "email" in sqluct_test.User`)
+
+	// Dialects without multiple result sets use sequential fallback.
+	st.Mapper.Dialect = sqluct.DialectSQLite3
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, name FROM users`).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "John"))
+	mock.ExpectQuery(`SELECT id FROM roles`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectCommit()
+
+	require.NoError(t, st.SelectMulti(context.Background(),
+		[]sqluct.ToSQL{sqluct.Plain("SELECT id, name FROM users"), sqluct.Plain("SELECT id FROM roles")},
+		[]interface{}{&user, &id},
+	))
+	assert.Equal(t, 2, id)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_SelectMulti_error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+	var id, cnt int
+
+	require.EqualError(t, st.SelectMulti(context.Background(), []sqluct.ToSQL{sqluct.Plain("SELECT 1")}, nil),
+		"number of queries and destinations mismatch")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM users`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM roles`).WillReturnError(errors.New("no table"))
+	mock.ExpectRollback()
+
+	err = st.SelectMulti(context.Background(),
+		[]sqluct.ToSQL{sqluct.Plain("SELECT id FROM users"), sqluct.Plain("SELECT COUNT(*) FROM roles")},
+		[]interface{}{&id, &cnt},
+	)
+	require.EqualError(t, err, "failed to select query #1: no table")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_TableExists(t *testing.T) {
+	for _, tc := range []struct {
+		dialect sqluct.Dialect
+		schema  string
+		query   string
+		args    []driver.Value
+	}{
+		{
+			dialect: sqluct.DialectPostgres,
+			query:   "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1",
+			args:    []driver.Value{"foo"},
+		},
+		{
+			dialect: sqluct.DialectPostgres,
+			schema:  "public",
+			query:   "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = $1 AND table_name = $2",
+			args:    []driver.Value{"public", "foo"},
+		},
+		{
+			dialect: sqluct.DialectMySQL,
+			query:   "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?",
+			args:    []driver.Value{"foo"},
+		},
+		{
+			dialect: sqluct.DialectSQLite3,
+			query:   `SELECT COUNT(*) FROM "main"."sqlite_master" WHERE name = ? AND type = ?`,
+			args:    []driver.Value{"foo", "table"},
+		},
+	} {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+
+		st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+		st.Mapper = &sqluct.Mapper{Dialect: tc.dialect}
+
+		if tc.dialect != sqluct.DialectPostgres {
+			st.Format = squirrel.Question
+		}
+
+		mock.ExpectQuery(tc.query).WithArgs(tc.args...).
+			WillReturnRows(sqlmock.NewRows([]string{"cnt"}).AddRow(1))
+
+		exists, err := st.TableExists(context.Background(), tc.schema, "foo")
+		require.NoError(t, err)
+		assert.True(t, exists)
+		require.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestStorage_ColumnExists(t *testing.T) {
+	for _, tc := range []struct {
+		dialect sqluct.Dialect
+		schema  string
+		query   string
+		args    []driver.Value
+	}{
+		{
+			dialect: sqluct.DialectPostgres,
+			query:   "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND column_name = $1 AND table_name = $2",
+			args:    []driver.Value{"bar", "foo"},
+		},
+		{
+			dialect: sqluct.DialectMySQL,
+			schema:  "app",
+			query:   "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = ? AND column_name = ? AND table_name = ?",
+			args:    []driver.Value{"app", "bar", "foo"},
+		},
+		{
+			dialect: sqluct.DialectSQLite3,
+			schema:  "aux",
+			query:   "SELECT COUNT(*) FROM pragma_table_info WHERE arg = ? AND name = ? AND schema = ?",
+			args:    []driver.Value{"foo", "bar", "aux"},
+		},
+	} {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+
+		st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+		st.Mapper = &sqluct.Mapper{Dialect: tc.dialect}
+
+		if tc.dialect != sqluct.DialectPostgres {
+			st.Format = squirrel.Question
+		}
+
+		mock.ExpectQuery(tc.query).WithArgs(tc.args...).
+			WillReturnRows(sqlmock.NewRows([]string{"cnt"}).AddRow(0))
+
+		exists, err := st.ColumnExists(context.Background(), tc.schema, "foo", "bar")
+		require.NoError(t, err)
+		assert.False(t, exists)
+		require.NoError(t, mock.ExpectationsWereMet())
+	}
+
+	st := sqluct.NewStorage(nil)
+
+	_, err := st.ColumnExists(context.Background(), "", "foo", "bar")
+	require.EqualError(t, err, "introspection is not supported for dialect")
+}
+
+func TestStorage_QueryRows(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+	var finished []error
+
+	st.Trace = func(ctx context.Context, stmt string, args []interface{}) (newCtx context.Context, onFinish func(error)) {
+		return ctx, func(err error) {
+			finished = append(finished, err)
+		}
+	}
+
+	mock.ExpectQuery("SELECT id FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).RowError(1, errors.New("failed")))
+
+	rows, err := st.QueryRows(context.Background(), st.QueryBuilder().Select("id").From("users"))
+	require.NoError(t, err)
+	assert.Empty(t, finished)
+
+	for rows.Next() {
+		var id int
+
+		require.NoError(t, rows.Scan(&id))
+	}
+
+	require.NoError(t, rows.Close())
+	require.NoError(t, rows.Close())
+	require.Len(t, finished, 1)
+	require.EqualError(t, finished[0], "failed")
+
+	mock.ExpectQuery("SELECT id FROM users").WillReturnError(errors.New("query failed"))
+
+	_, err = st.QueryRows(context.Background(), st.QueryBuilder().Select("id").From("users"))
+	require.Error(t, err)
+	require.Len(t, finished, 2)
+	require.Error(t, finished[1])
+
+	mock.ExpectQuery("SELECT id FROM users").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	plain, err := st.Query(context.Background(), st.QueryBuilder().Select("id").From("users"))
+	require.NoError(t, err)
+	require.Len(t, finished, 3)
+	require.NoError(t, finished[2])
+	require.NoError(t, plain.Close())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_QueryMaps(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	traced := false
+
+	st.Trace = func(ctx context.Context, stmt string, args []interface{}) (newCtx context.Context, onFinish func(error)) {
+		traced = true
+
+		assert.Equal(t, "SELECT id, name FROM users WHERE id > $1", stmt)
+
+		return ctx, func(err error) {}
+	}
+
+	mock.ExpectQuery(`SELECT id, name FROM users WHERE id > \$1`).WithArgs(0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).
+			AddRow(1, []byte("John")).
+			AddRow(2, nil))
+
+	rows, err := st.QueryMaps(context.Background(), st.QueryBuilder().Select("id", "name").From("users").Where("id > ?", 0))
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"id": int64(1), "name": "John"},
+		{"id": int64(2), "name": nil},
+	}, rows)
+	assert.True(t, traced)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_QueryMapsFunc(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+	mock.ExpectQuery(`SELECT id FROM users`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).AddRow(3))
+
+	var ids []interface{}
+
+	err = st.QueryMapsFunc(context.Background(), sqluct.Plain("SELECT id FROM users"), func(row map[string]interface{}) error {
+		ids = append(ids, row["id"])
+
+		if len(ids) == 2 {
+			return errors.New("enough")
+		}
+
+		return nil
+	})
+	require.EqualError(t, err, "enough")
+	assert.Equal(t, []interface{}{int64(1), int64(2)}, ids)
+
+	mock.ExpectQuery(`SELECT id FROM users`).WillReturnError(errors.New("failed"))
+
+	_, err = st.QueryMaps(context.Background(), sqluct.Plain("SELECT id FROM users"))
+	require.EqualError(t, err, "failed")
+
+	mock.ExpectQuery(`SELECT id FROM users`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).RowError(0, errors.New("row failed")))
+
+	_, err = st.QueryMaps(context.Background(), sqluct.Plain("SELECT id FROM users"))
+	require.EqualError(t, err, "row failed")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_ScanMode(t *testing.T) {
+	type Row struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	ctx := context.Background()
+
+	var rows []Row
+
+	mock.ExpectQuery("SELECT \\* FROM rows").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "extra"}).AddRow(1, "a", "x"))
+	require.EqualError(t, st.Select(ctx, sqluct.Plain("SELECT * FROM rows"), &rows),
+		"missing destination name extra in *[]sqluct_test.Row")
+
+	st.ScanMode = sqluct.ScanUnsafe
+
+	mock.ExpectQuery("SELECT \\* FROM rows").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "extra"}).AddRow(1, "a", "x"))
+	require.NoError(t, st.Select(ctx, sqluct.Plain("SELECT * FROM rows"), &rows))
+	assert.Equal(t, []Row{{ID: 1, Name: "a"}}, rows)
+
+	st.ScanMode = sqluct.ScanStrict
+
+	mock.ExpectQuery("SELECT id FROM rows").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	require.EqualError(t, st.Select(ctx, sqluct.Plain("SELECT id FROM rows"), &rows), "missing result columns")
+
+	var row Row
+
+	mock.ExpectQuery("SELECT id, name FROM rows").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "b"))
+	require.NoError(t, st.Select(ctx, sqluct.Plain("SELECT id, name FROM rows"), &row))
+	assert.Equal(t, Row{ID: 2, Name: "b"}, row)
+
+	var cnt int
+
+	mock.ExpectQuery("SELECT COUNT").
+		WillReturnRows(sqlmock.NewRows([]string{"cnt"}).AddRow(3))
+	require.NoError(t, st.Select(ctx, sqluct.Plain("SELECT COUNT(*) AS cnt FROM rows"), &cnt))
+	assert.Equal(t, 3, cnt)
+
+	mock.ExpectQuery("SELECT id, name FROM rows").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	require.ErrorIs(t, st.Select(ctx, sqluct.Plain("SELECT id, name FROM rows"), &row), sql.ErrNoRows)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_QueryBuilderFormat(t *testing.T) {
+	st := sqluct.NewStorage(nil)
+	st.Format = squirrel.Question
+
+	assertStatementArgs(t, "SELECT id FROM users WHERE name = $1", []interface{}{"John"},
+		st.QueryBuilderFormat(squirrel.Dollar).Select("id").From("users").Where(squirrel.Eq{"name": "John"}))
+	assertStatementArgs(t, "SELECT id FROM users WHERE name = ?", []interface{}{"John"},
+		st.QueryBuilder().Select("id").From("users").Where(squirrel.Eq{"name": "John"}))
+	assertStatementArgs(t, "DELETE FROM users WHERE id = @p1", []interface{}{1},
+		st.DeleteStmt("users").Where(squirrel.Eq{"id": 1}).PlaceholderFormat(squirrel.AtP))
+}
+
+func TestStorage_BaseBuilder(t *testing.T) {
+	st := sqluct.NewStorage(nil)
+	st.BaseBuilder = squirrel.StatementBuilder.Where(squirrel.Eq{"tenant_id": 1})
+
+	assertStatementArgs(t, "SELECT id FROM rows WHERE tenant_id = $1", []interface{}{1},
+		st.QueryBuilder().Select("id").From("rows"))
+	assertStatementArgs(t, "DELETE FROM rows WHERE tenant_id = $1 AND id = $2", []interface{}{1, 2},
+		st.DeleteStmt("rows").Where(squirrel.Eq{"id": 2}))
+
+	st.BaseBuilder = squirrel.StatementBuilderType{}
+
+	assertStatement(t, "SELECT id FROM rows", st.QueryBuilder().Select("id").From("rows"))
+}
+
+func TestStorage_CommentFromContext(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	st.CommentFromContext = func(ctx context.Context) string {
+		return "application='app',route='" + sqluct.LabelFromContext(ctx) + "'"
+	}
+
+	var traced []string
+
+	st.Trace = func(ctx context.Context, stmt string, args []interface{}) (context.Context, func(error)) {
+		traced = append(traced, stmt)
+
+		return ctx, func(error) {}
+	}
+
+	ctx := sqluct.LabelToContext(context.Background(), "*/ DROP TABLE users; /*")
+
+	mock.ExpectExec("/*application='app',route='* / DROP TABLE users; / *'*/ DELETE FROM users WHERE id = $1").
+		WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err = st.Exec(ctx, st.DeleteStmt("users").Where(squirrel.Eq{"id": 1}))
+	require.NoError(t, err)
+
+	mock.ExpectQuery("/*application='app',route=''*/ SELECT id FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	var ids []int
+
+	require.NoError(t, st.Select(context.Background(), st.QueryBuilder().Select("id").From("users"), &ids))
+
+	st.CommentFromContext = func(ctx context.Context) string { return "" }
+
+	mock.ExpectQuery("SELECT id FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	rows, err := st.Query(context.Background(), st.QueryBuilder().Select("id").From("users"))
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+
+	assert.Equal(t, []string{
+		"/*application='app',route='* / DROP TABLE users; / *'*/ DELETE FROM users WHERE id = $1",
+		"/*application='app',route=''*/ SELECT id FROM users",
+		"SELECT id FROM users",
+	}, traced)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+type fakeSelector struct {
+	sqluct.Selector
+	stmt string
+}
+
+func (f *fakeSelector) Select(_ context.Context, qb sqluct.ToSQL, dest interface{}) error {
+	f.stmt, _, _ = qb.ToSql()
+	*(dest.(*int)) = 42
+
+	return nil
+}
+
+func TestSelector_fake(t *testing.T) {
+	countUsers := func(ctx context.Context, s sqluct.Selector) (int, error) {
+		var cnt int
+
+		err := s.Select(ctx, squirrel.Select("COUNT(*)").From("users"), &cnt)
+
+		return cnt, err
+	}
+
+	f := &fakeSelector{}
+	cnt, err := countUsers(context.Background(), f)
+	require.NoError(t, err)
+	assert.Equal(t, 42, cnt)
+	assert.Equal(t, "SELECT COUNT(*) FROM users", f.stmt)
+}
+
+func TestStmtStruct(t *testing.T) {
+	type Base struct {
+		TenantID int `db:"tenant_id"`
+	}
+
+	type Filter struct {
+		Base
+		Role string `db:"role,omitempty"`
+		Name string `db:"name"`
+	}
+
+	f := Filter{Base: Base{TenantID: 1}, Name: "J%"}
+
+	stmt, args, err := sqluct.StmtStruct("SELECT id FROM users WHERE role = ? AND name LIKE ? AND tenant_id = ?", f).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM users WHERE role = ? AND name LIKE ? AND tenant_id = ?", stmt)
+	assert.Equal(t, []interface{}{"", "J%", 1}, args)
+
+	stmt, args, err = sqluct.StmtStruct(
+		"SELECT id::text, ':role' FROM users WHERE tenant_id = :tenant_id AND (name LIKE :name OR nick LIKE :name)", &f).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id::text, ':role' FROM users WHERE tenant_id = ? AND (name LIKE ? OR nick LIKE ?)", stmt)
+	assert.Equal(t, []interface{}{1, "J%", "J%"}, args)
+
+	_, _, err = sqluct.StmtStruct("SELECT id FROM users WHERE email = :email", f).ToSql()
+	require.EqualError(t, err, `unknown column "email" in statement`)
+}
+
+func TestStorage_Explain(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	st.Mapper = &sqluct.Mapper{Dialect: sqluct.DialectPostgres}
+	ctx := context.Background()
+	qb := st.QueryBuilder().Select("id").From("users").Where(squirrel.Eq{"id": 1})
+
+	mock.ExpectQuery("EXPLAIN (ANALYZE, FORMAT TEXT) SELECT id FROM users WHERE id = $1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).
+			AddRow("Index Only Scan using users_pkey on users").
+			AddRow([]byte("  Index Cond: (id = 1)")))
+
+	plan, err := st.Explain(ctx, qb, true)
+	require.NoError(t, err)
+	assert.Equal(t, "Index Only Scan using users_pkey on users\n  Index Cond: (id = 1)", plan)
+
+	st.Mapper.Dialect = sqluct.DialectSQLite3
+
+	mock.ExpectQuery("EXPLAIN QUERY PLAN SELECT id FROM users WHERE id = $1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent", "notused", "detail"}).
+			AddRow(2, 0, 0, "SEARCH users USING INTEGER PRIMARY KEY (rowid=?)"))
+
+	plan, err = st.Explain(ctx, qb, false)
+	require.NoError(t, err)
+	assert.Equal(t, "SEARCH users USING INTEGER PRIMARY KEY (rowid=?)", plan)
+
+	st.Mapper.Dialect = sqluct.DialectUnknown
+
+	_, err = st.Explain(ctx, qb, false)
+	require.EqualError(t, err, "explain is not supported for dialect")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_StatementErrors(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	ctx := context.Background()
+	qb := st.DeleteStmt("users").Where(squirrel.Eq{"email": "john@example.com"})
+
+	mock.ExpectExec("DELETE FROM users WHERE email = $1").WillReturnError(errors.New("failed"))
+
+	_, err = st.Exec(ctx, qb)
+	require.EqualError(t, err, "failed")
+
+	st.StatementErrors = true
+
+	mock.ExpectExec("DELETE FROM users WHERE email = $1").WillReturnError(errors.New("failed"))
+
+	_, err = st.Exec(ctx, qb)
+	require.EqualError(t, err, "failed (query: DELETE FROM users WHERE email = $1)")
+
+	var se sqluct.StatementError
+
+	require.True(t, errors.As(err, &se))
+	assert.Equal(t, "DELETE FROM users WHERE email = $1", se.Query)
+	assert.Nil(t, se.Args)
+
+	st.StatementErrorArgs = true
+	st.RedactArgs = sqluct.RedactArgsByColumn("email")
+
+	mock.ExpectQuery("SELECT id FROM users WHERE email = $1").WillReturnError(errors.New("failed"))
+
+	var ids []int
+
+	err = st.Select(ctx, st.QueryBuilder().Select("id").From("users").Where(squirrel.Eq{"email": "john@example.com"}), &ids)
+	require.True(t, errors.As(err, &se))
+	assert.Equal(t, []interface{}{sqluct.Redacted}, se.Args)
+
+	mock.ExpectQuery("SELECT id FROM users").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	var id int
+
+	err = st.Select(ctx, st.QueryBuilder().Select("id").From("users"), &id)
+	require.Equal(t, sql.ErrNoRows, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_SelectJoined(t *testing.T) {
+	type User struct {
+		ID     int    `db:"id"`
+		RoleID int    `db:"role_id"`
+		Name   string `db:"name"`
+	}
+
+	type Role struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	type UserRole struct {
+		User User `db:"u"`
+		Role Role `db:"r"`
+	}
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	st.IdentifierQuoter = sqluct.QuoteANSI
+
+	rf := st.MakeReferencer()
+	u := &User{}
+	r := &Role{}
+	rf.AddTableAlias(u, "u")
+	rf.AddTableAlias(r, "r")
+
+	q := st.QueryBuilder().Select().
+		From(rf.Fmt("%s AS %s", rf.Q("users"), u)).
+		Join(rf.Fmt("%s AS %s ON %s = %s", rf.Q("roles"), r, &r.ID, &u.RoleID))
+
+	mock.ExpectQuery(`SELECT "u"."id" AS "u.id", "u"."name" AS "u.name", "u"."role_id" AS "u.role_id", ` +
+		`"r"."id" AS "r.id", "r"."name" AS "r.name" ` +
+		`FROM "users" AS "u" JOIN "roles" AS "r" ON "r"."id" = "u"."role_id"`).
+		WillReturnRows(sqlmock.NewRows([]string{"u.id", "u.name", "u.role_id", "r.id", "r.name"}).
+			AddRow(1, "John", 2, 2, "admin"))
+
+	var rows []UserRole
+
+	require.NoError(t, st.SelectJoined(context.Background(), rf, q, &rows, u, r))
+	assert.Equal(t, []UserRole{{User: User{ID: 1, RoleID: 2, Name: "John"}, Role: Role{ID: 2, Name: "admin"}}}, rows)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_SelectStmt_cache(t *testing.T) {
+	st := sqluct.NewStorage(nil)
+
+	assertStatement(t, "SELECT a, meta, e, b, c FROM sample", st.SelectStmt("sample", Sample{}))
+	assertStatement(t, "SELECT a, meta, e, b, c FROM sample", st.SelectStmt("sample", Sample{}))
+	assertStatement(t, "SELECT a, b FROM sample", st.SelectStmt("sample", Sample{}, sqluct.Columns("a", "b")))
+
+	st.SetQuoter(sqluct.QuoteANSI)
+
+	assertStatement(t, `SELECT "a", "meta", "e", "b", "c" FROM "sample"`, st.SelectStmt("sample", Sample{}))
+
+	st.Mapper = &sqluct.Mapper{ColumnNameMapper: strings.ToUpper}
+	st.ClearCache()
+
+	assertStatement(t, `SELECT "A", "META", "E", "B", "C" FROM "sample"`, st.SelectStmt("sample", Sample{}))
+
+	st.SetMapper(&sqluct.Mapper{})
+
+	assertStatement(t, `SELECT "a", "meta", "e", "b", "c" FROM "sample"`, st.SelectStmt("sample", Sample{}))
+
+	// Closures of the same function literal with different state.
+	quoter := func(q string) func(tableAndColumn ...string) string {
+		return func(tableAndColumn ...string) string {
+			return q + strings.Join(tableAndColumn, q+"."+q) + q
+		}
+	}
+
+	cp := *st
+	cp.SetQuoter(quoter("`"))
+	st.SetQuoter(quoter(`"`))
+
+	assertStatement(t, "SELECT `a`, `meta`, `e`, `b`, `c` FROM `sample`", cp.SelectStmt("sample", Sample{}))
+	assertStatement(t, `SELECT "a", "meta", "e", "b", "c" FROM "sample"`, st.SelectStmt("sample", Sample{}))
+
+	st.Mapper.MaxCachedTypes = 1
+
+	assertStatement(t, `SELECT "a", "meta", "e", "b", "c" FROM "sample"`, st.SelectStmt("sample", Sample{}))
+	assertStatement(t, `SELECT "a", "meta", "e", "b", "c" FROM "sample"`, st.SelectStmt("sample", &Sample{}))
+
+	var zero sqluct.Storage
+
+	assertStatement(t, "SELECT a, meta, e, b, c FROM sample", zero.SelectStmt("sample", Sample{}))
+}
+
+func BenchmarkStorage_SelectStmt(b *testing.B) {
+	st := sqluct.NewStorage(nil)
+	st.IdentifierQuoter = sqluct.QuoteANSI
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		_, _, err := st.SelectStmt("sample", Sample{}).ToSql()
+		if err != nil {
+			b.Fail()
+		}
+	}
+}
+
+func BenchmarkStorage_SelectStmt_uncached(b *testing.B) {
+	st := sqluct.NewStorage(nil)
+	st.IdentifierQuoter = sqluct.QuoteANSI
+	noop := func(o *sqluct.Options) {}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		_, _, err := st.SelectStmt("sample", Sample{}, noop).ToSql()
+		if err != nil {
+			b.Fail()
+		}
+	}
+}
+
+func TestStorage_Replica(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	rdb, rmock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+	st.Replica = sqlx.NewDb(rdb, "mock")
+	ctx := context.Background()
+	qb := st.QueryBuilder().Select("name").From("users").Where(squirrel.Eq{"id": 1})
+
+	var name string
+
+	rmock.ExpectQuery("SELECT name FROM users WHERE id = $1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("replica"))
+
+	require.NoError(t, st.Select(ctx, qb, &name))
+	assert.Equal(t, "replica", name)
+
+	rmock.ExpectQuery("SELECT name FROM users WHERE id = $1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("replica"))
+
+	rows, err := st.Query(ctx, qb)
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+
+	mock.ExpectExec("UPDATE users SET name = $1 WHERE id = $2").WithArgs("primary", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err = st.Exec(ctx, st.UpdateStmt("users", nil).Set("name", "primary").Where(squirrel.Eq{"id": 1}))
+	require.NoError(t, err)
+
+	mock.ExpectQuery("SELECT name FROM users WHERE id = $1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("primary"))
+
+	require.NoError(t, st.Select(sqluct.ReadFromPrimary(ctx), qb, &name))
+	assert.Equal(t, "primary", name)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT name FROM users WHERE id = $1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("tx"))
+	mock.ExpectCommit()
+
+	require.NoError(t, st.InTx(ctx, func(ctx context.Context) error {
+		return st.Select(ctx, qb, &name)
+	}))
+	assert.Equal(t, "tx", name)
+
+	mock.ExpectQuery("EXPLAIN (ANALYZE, FORMAT TEXT) SELECT name FROM users WHERE id = $1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow("Seq Scan on users"))
+
+	st.Mapper = &sqluct.Mapper{Dialect: sqluct.DialectPostgres}
+	plan, err := st.Explain(ctx, qb, true)
+	require.NoError(t, err)
+	assert.Equal(t, "Seq Scan on users", plan)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.NoError(t, rmock.ExpectationsWereMet())
+}
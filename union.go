@@ -0,0 +1,117 @@
+package sqluct
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+var errEmptyUnion = errors.New("at least one select statement is required for union")
+
+// UnionStmt is a compound statement of SELECT statements combined with UNION or UNION ALL.
+//
+// ORDER BY, LIMIT and OFFSET apply to the whole union and must be set with UnionStmt methods,
+// they must not be set on combined statements.
+type UnionStmt struct {
+	all     bool
+	parts   []squirrel.SelectBuilder
+	orderBy []string
+	limit   string
+	offset  string
+	format  squirrel.PlaceholderFormat
+}
+
+// Union combines SELECT statements with UNION (or UNION ALL if all is true).
+//
+// Placeholders of combined statements are numbered together according to PlaceholderFormat,
+// default squirrel.Question, use Storage.Union to have format of Storage.
+//
+//	q := sqluct.Union(true, activeUsers, archivedUsers).OrderBy("name").Limit(10)
+func Union(all bool, builders ...squirrel.SelectBuilder) UnionStmt {
+	return UnionStmt{all: all, parts: builders}
+}
+
+// PlaceholderFormat sets placeholder format of the union.
+func (u UnionStmt) PlaceholderFormat(f squirrel.PlaceholderFormat) UnionStmt {
+	u.format = f
+
+	return u
+}
+
+// OrderBy adds ORDER BY expressions to the union.
+func (u UnionStmt) OrderBy(orderBys ...string) UnionStmt {
+	u.orderBy = append(u.orderBy[:len(u.orderBy):len(u.orderBy)], orderBys...)
+
+	return u
+}
+
+// Limit sets LIMIT of the union.
+func (u UnionStmt) Limit(limit uint64) UnionStmt {
+	u.limit = strconv.FormatUint(limit, 10)
+
+	return u
+}
+
+// Offset sets OFFSET of the union.
+func (u UnionStmt) Offset(offset uint64) UnionStmt {
+	u.offset = strconv.FormatUint(offset, 10)
+
+	return u
+}
+
+// ToSql renders union statement.
+func (u UnionStmt) ToSql() (string, []interface{}, error) { //nolint // Method name matches ext. implementation.
+	if len(u.parts) == 0 {
+		return "", nil, errEmptyUnion
+	}
+
+	var (
+		res  strings.Builder
+		args []interface{}
+	)
+
+	sep := " UNION "
+	if u.all {
+		sep = " UNION ALL "
+	}
+
+	for i, p := range u.parts {
+		query, a, err := p.PlaceholderFormat(squirrel.Question).ToSql()
+		if err != nil {
+			return "", nil, err
+		}
+
+		if i > 0 {
+			res.WriteString(sep)
+		}
+
+		res.WriteString(query)
+
+		args = append(args, a...)
+	}
+
+	if len(u.orderBy) > 0 {
+		res.WriteString(" ORDER BY " + strings.Join(u.orderBy, ", "))
+	}
+
+	if u.limit != "" {
+		res.WriteString(" LIMIT " + u.limit)
+	}
+
+	if u.offset != "" {
+		res.WriteString(" OFFSET " + u.offset)
+	}
+
+	if u.format == nil {
+		return res.String(), args, nil
+	}
+
+	query, err := u.format.ReplacePlaceholders(res.String())
+	if err != nil {
+		return "", nil, err
+	}
+
+	return query, args, nil
+}
@@ -0,0 +1,48 @@
+package sqluct_test
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/Masterminds/squirrel"
+	"github.com/bool64/sqluct"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnion(t *testing.T) {
+	active := squirrel.Select("id", "name").From("users").Where(squirrel.Eq{"status": "active"})
+	archived := squirrel.Select("id", "name").From("archived_users").Where(squirrel.Gt{"age": 18})
+
+	assertStatementArgs(t, "SELECT id, name FROM users WHERE status = ? UNION "+
+		"SELECT id, name FROM archived_users WHERE age > ?", []interface{}{"active", 18},
+		sqluct.Union(false, active, archived))
+
+	_, _, err := sqluct.Union(true).ToSql()
+	require.EqualError(t, err, "at least one select statement is required for union")
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+
+	st := sqluct.NewStorage(sqlx.NewDb(db, "mock"))
+
+	mock.ExpectQuery("SELECT id, name FROM users WHERE status = $1 UNION ALL "+
+		"SELECT id, name FROM archived_users WHERE age > $2 ORDER BY name, id LIMIT 10 OFFSET 20").
+		WithArgs("active", 18).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "John"))
+
+	var rows []struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	// Builders with Storage placeholder format are renumbered too.
+	active = st.QueryBuilder().Select("id", "name").From("users").Where(squirrel.Eq{"status": "active"})
+
+	require.NoError(t, st.Select(context.Background(),
+		st.Union(true, active, archived).OrderBy("name").OrderBy("id").Limit(10).Offset(20), &rows))
+	assert.Len(t, rows, 1)
+	require.NoError(t, mock.ExpectationsWereMet())
+}